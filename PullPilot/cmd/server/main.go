@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,6 +21,7 @@ import (
 	// "github.com/keploy/PullPilot/internal/api"
 	"github.com/keploy/PullPilot/internal/analyzer/api"
 	"github.com/keploy/PullPilot/internal/config"
+	"github.com/keploy/PullPilot/internal/event"
 )
 
 type PullRequest struct {
@@ -74,7 +77,7 @@ func extractOwnerAndRepo(PullRequest_url string) (string, string, error) {
 	return owner, repo, nil
 }
 
-func startServer(wg *sync.WaitGroup) {
+func startServer(wg *sync.WaitGroup, webhookSecret string) {
 	defer wg.Done()
 	fmt.Printf("Starting server on port 6969 holalal\n")
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -129,7 +132,7 @@ func startServer(wg *sync.WaitGroup) {
 		curlCmd := exec.Command("curl", "-X", "POST",
 			"-H", "Content-Type: application/json",
 			"-H", "X-GitHub-Event: pull_request",
-			"-H", "X-Hub-Signature-256: sha256=dummy",
+			"-H", "X-Hub-Signature-256: "+event.SignGitHubPayload(webhookSecret, jsonBody),
 			"-d", string(jsonBody),
 			"http://localhost:8080/webhook/github")
 
@@ -162,10 +165,20 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// The run is started by posting a webhook to this server, which only accepts signed ones.
+	// Without a configured secret, sign with one that lives as long as the process.
+	if cfg.GitHubWebhookSecret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to generate webhook secret: %v", err)
+		}
+		cfg.GitHubWebhookSecret = hex.EncodeToString(secret)
+	}
+
 	var wg sync.WaitGroup
 
 	wg.Add(1)
-	go startServer(&wg)
+	go startServer(&wg, cfg.GitHubWebhookSecret)
 
 	router := api.NewRouter(cfg)
 
@@ -2,10 +2,13 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/keploy/PullPilot/internal/analyzer/llm"
 	"github.com/keploy/PullPilot/internal/config"
 	"github.com/keploy/PullPilot/internal/event"
+	"github.com/keploy/PullPilot/internal/shared"
 )
 
 func NewRouter(cfg *config.Config) *gin.Engine {
@@ -28,11 +31,46 @@ func NewRouter(cfg *config.Config) *gin.Engine {
 
 		api.POST("/analyze", webhookHandler.HandleManualAnalysis)
 
+		api.GET("/metrics", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"ai_parsing": llm.ParseMetrics(),
+			})
+		})
+
+		api.GET("/usage", func(c *gin.Context) {
+			report := shared.GetUsage(c.Query("repo"), time.Now())
+			report.MonthlyLimit = cfg.AIMonthlyBudgetUSD
+			c.JSON(http.StatusOK, report)
+		})
+
 		api.GET("/results/:id", func(c *gin.Context) {
+			run, ok := shared.GetRun(c.Param("id"))
+			if !ok {
+				c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+				return
+			}
 
-			c.JSON(http.StatusNotImplemented, gin.H{
-				"status": "not implemented",
-			})
+			c.JSON(http.StatusOK, run)
+		})
+
+		api.GET("/results/:id/sbom", func(c *gin.Context) {
+			run, ok := shared.GetRun(c.Param("id"))
+			if !ok {
+				c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+				return
+			}
+
+			sbom, contentType := run.CycloneDXSBOM, "application/vnd.cyclonedx+json"
+			if c.DefaultQuery("format", "cyclonedx") == "spdx" {
+				sbom, contentType = run.SPDXSBOM, "application/spdx+json"
+			}
+			if len(sbom) == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "SBOM not available for this run"})
+				return
+			}
+
+			c.Header("Content-Disposition", "attachment; filename=\""+run.ID+"-sbom.json\"")
+			c.Data(http.StatusOK, contentType, sbom)
 		})
 	}
 
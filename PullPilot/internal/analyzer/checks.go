@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/keploy/PullPilot/pkg/github"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const checkRunName = "PullPilot"
+
+// checkRunCompletionTimeout bounds completing a check run. Completion does not use the
+// analysis context, which may have run out by then and would leave the run in progress.
+const checkRunCompletionTimeout = time.Minute
+
+// fetchPullRequest returns the pull request of a GitHub job, or nil when it is unavailable.
+func (o *Orchestrator) fetchPullRequest(ctx context.Context, job *Job) *github.PullRequest {
+	if job.Provider != "github" {
+		return nil
+	}
+	pr, err := job.client.GetPullRequest(ctx, job.RepoOwner, job.RepoName, job.PRNumber)
+	if err != nil {
+		log.Printf("Warning: Failed to fetch pull request metadata: %v", err)
+		return nil
+	}
+	return pr
+}
+
+// createCheckRun queues the check run of a job on the head commit. It returns 0 when checks
+// are disabled or cannot be created, for instance because the token is not a GitHub App's;
+// the quality gate is then reported as a commit status instead.
+func (o *Orchestrator) createCheckRun(ctx context.Context, job *Job, pr *github.PullRequest) int64 {
+	if !o.cfg.EnableChecks {
+		return 0
+	}
+	if pr == nil {
+		log.Printf("Warning: No check run without the pull request metadata, reporting the quality gate as a commit status")
+		return 0
+	}
+	id, err := job.client.CreateCheckRun(ctx, job.RepoOwner, job.RepoName, checkRunName, pr.Head.SHA)
+	if err != nil {
+		log.Printf("Warning: Failed to create the check run, reporting the quality gate as a commit status: %v", err)
+		return 0
+	}
+	log.Printf("Created check run %d for %s/%s PR #%d", id, job.RepoOwner, job.RepoName, job.PRNumber)
+	return id
+}
+
+func (o *Orchestrator) startCheckRun(ctx context.Context, job *Job, id int64) {
+	if id == 0 {
+		return
+	}
+	if err := job.client.StartCheckRun(ctx, job.RepoOwner, job.RepoName, id); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// completeCheckRun publishes the report as the check summary and the issues as annotations.
+// The conclusion is that of a quality gate configured on the server. The run is neutral
+// without one, as a pull request must not be able to turn its own check green.
+func (o *Orchestrator) completeCheckRun(job *Job, id int64, gate *models.GateResult, report string, issues []*models.Issue) {
+	if id == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkRunCompletionTimeout)
+	defer cancel()
+
+	conclusion, title := "neutral", fmt.Sprintf("%d issues found", len(issues))
+	if gate != nil && gate.ServerPolicy && gate.Passed {
+		conclusion, title = "success", fmt.Sprintf("Quality gate passed, %d issues found", len(issues))
+	} else if gate != nil && gate.ServerPolicy {
+		conclusion, title = "failure", fmt.Sprintf("Quality gate failed: %d rules violated", len(gate.Reasons))
+	}
+
+	err := job.client.CompleteCheckRun(ctx, job.RepoOwner, job.RepoName, id, conclusion, title, report, github.IssueAnnotations(issues))
+	if err != nil {
+		log.Printf("Warning: Failed to complete check run: %v", err)
+	}
+}
+
+// failCheckRun completes a check run whose analysis could not run.
+func (o *Orchestrator) failCheckRun(job *Job, id int64, cause error) {
+	if id == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkRunCompletionTimeout)
+	defer cancel()
+	err := job.client.CompleteCheckRun(ctx, job.RepoOwner, job.RepoName, id, "failure", "Analysis failed", cause.Error(), nil)
+	if err != nil {
+		log.Printf("Warning: Failed to complete check run: %v", err)
+	}
+}
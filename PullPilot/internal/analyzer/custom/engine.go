@@ -0,0 +1,245 @@
+package custom
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/keploy/PullPilot/internal/config"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// languageExtensions maps the language names accepted in rule configs to file extensions.
+var languageExtensions = map[string][]string{
+	"go":         {".go"},
+	"python":     {".py"},
+	"javascript": {".js", ".jsx", ".mjs", ".cjs"},
+	"typescript": {".ts", ".tsx"},
+	"java":       {".java"},
+}
+
+// suppressionRegex matches inline suppressions such as "// pullpilot:ignore no-println, todo-ticket".
+// A suppression applies to its own line and to the line below it.
+var suppressionRegex = regexp.MustCompile(`pullpilot:ignore\s+([\w.\-]+(?:\s*,\s*[\w.\-]+)*)`)
+
+// rule is a compiled RuleConfig.
+type rule struct {
+	id           string
+	extensions   map[string]bool
+	paths        []string
+	pattern      *regexp.Regexp
+	literal      string
+	structural   *structuralPattern
+	mustNotMatch *regexp.Regexp
+	severity     models.Severity
+	message      string
+	suggestion   string
+}
+
+func compileRule(cfg config.RuleConfig) (*rule, error) {
+	if cfg.ID == "" {
+		return nil, errors.New("rule has no id")
+	}
+	set := 0
+	for _, field := range []string{cfg.Pattern, cfg.Literal, cfg.Structural} {
+		if field != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("rule %s: exactly one of pattern, literal and structural is required", cfg.ID)
+	}
+
+	r := &rule{
+		id:         cfg.ID,
+		paths:      cfg.Paths,
+		literal:    cfg.Literal,
+		severity:   models.SeverityWarning,
+		message:    cfg.Message,
+		suggestion: cfg.Suggestion,
+	}
+	if r.message == "" {
+		r.message = fmt.Sprintf("Line violates the %s rule.", cfg.ID)
+	}
+
+	if len(cfg.Languages) > 0 {
+		r.extensions = make(map[string]bool)
+		for _, language := range cfg.Languages {
+			extensions, ok := languageExtensions[strings.ToLower(language)]
+			if !ok {
+				return nil, fmt.Errorf("rule %s: unknown language %q", cfg.ID, language)
+			}
+			for _, ext := range extensions {
+				r.extensions[ext] = true
+			}
+		}
+	}
+
+	var err error
+	if cfg.Pattern != "" {
+		if r.pattern, err = regexp.Compile(cfg.Pattern); err != nil {
+			return nil, fmt.Errorf("rule %s: invalid pattern: %w", cfg.ID, err)
+		}
+	}
+	if cfg.Structural != "" {
+		if r.structural, err = compileStructural(cfg.Structural, cfg.Languages); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", cfg.ID, err)
+		}
+	}
+	if cfg.MustNotMatch != "" {
+		if r.mustNotMatch, err = regexp.Compile(cfg.MustNotMatch); err != nil {
+			return nil, fmt.Errorf("rule %s: invalid must_not_match: %w", cfg.ID, err)
+		}
+	}
+	if cfg.Severity != "" {
+		if r.severity, err = models.ParseSeverity(cfg.Severity); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", cfg.ID, err)
+		}
+	}
+	return r, nil
+}
+
+// compileRules compiles the rules of a repository config. Invalid rules are skipped and
+// reported through the returned errors so that one typo does not disable every rule.
+func compileRules(configs []config.RuleConfig) ([]*rule, []error) {
+	var rules []*rule
+	var errs []error
+	for _, cfg := range configs {
+		r, err := compileRule(cfg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules, errs
+}
+
+func (r *rule) appliesTo(path string) bool {
+	if r.extensions != nil && !r.extensions[filepath.Ext(path)] {
+		return false
+	}
+	if len(r.paths) == 0 {
+		return true
+	}
+	for _, pattern := range r.paths {
+		if config.MatchGlob(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rule) matches(line string) bool {
+	if r.pattern != nil && !r.pattern.MatchString(line) {
+		return false
+	}
+	if r.literal != "" && !strings.Contains(line, r.literal) {
+		return false
+	}
+	return r.mustNotMatch == nil || !r.mustNotMatch.MatchString(line)
+}
+
+// evaluateRules runs the rules against the added lines of every file.
+func evaluateRules(rules []*rule, files []*models.File) []*models.Issue {
+	var issues []*models.Issue
+	for _, file := range files {
+		var applicable []*rule
+		for _, r := range rules {
+			if r.appliesTo(file.Path) {
+				applicable = append(applicable, r)
+			}
+		}
+		if len(applicable) == 0 {
+			continue
+		}
+
+		added := addedLines(file)
+		suppressed := suppressions(file.Content)
+		issues = append(issues, evaluateStructural(applicable, file, added, suppressed)...)
+		for _, number := range sortedLines(added) {
+			for _, r := range applicable {
+				if r.structural != nil || !r.matches(added[number]) || suppressed[number][r.id] {
+					continue
+				}
+				issues = append(issues, &models.Issue{
+					Path:        file.Path,
+					Line:        number,
+					Title:       fmt.Sprintf("Rule %s", r.id),
+					Description: r.message,
+					Severity:    r.severity,
+					Suggestion:  r.suggestion,
+					Source:      "custom-rule",
+					Category:    r.id,
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// evaluateStructural runs the structural rules against a file and reports the matches that
+// start on added lines, with the matched code and the metavariables expanded in the message.
+func evaluateStructural(rules []*rule, file *models.File, added map[int]string, suppressed map[int]map[string]bool) []*models.Issue {
+	var language string
+	var tree *syntaxNode
+	parsed := false
+
+	var issues []*models.Issue
+	for _, r := range rules {
+		if r.structural == nil {
+			continue
+		}
+		if !parsed {
+			var ok bool
+			if language, tree, ok = parseSource(file.Path, file.Content); !ok {
+				return nil
+			}
+			parsed = true
+		}
+
+		for _, match := range r.structural.findAll(language, tree) {
+			if _, ok := added[match.line]; !ok || suppressed[match.line][r.id] {
+				continue
+			}
+			snippet := sourceSnippet(file.Content, match.start, match.end)
+			if r.mustNotMatch != nil && r.mustNotMatch.MatchString(snippet) {
+				continue
+			}
+			issues = append(issues, &models.Issue{
+				Path:        file.Path,
+				Line:        match.line,
+				Title:       fmt.Sprintf("Rule %s", r.id),
+				Description: fmt.Sprintf("%s\n\nMatched: `%s`", expandMetavariables(r.message, file.Content, match.bindings), snippet),
+				Severity:    r.severity,
+				Suggestion:  r.suggestion,
+				Source:      "custom-rule",
+				Category:    r.id,
+			})
+		}
+	}
+	return issues
+}
+
+// suppressions returns the rule IDs suppressed on each line of a file.
+func suppressions(content string) map[int]map[string]bool {
+	suppressed := make(map[int]map[string]bool)
+	for i, line := range strings.Split(content, "\n") {
+		match := suppressionRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		for _, id := range strings.Split(match[1], ",") {
+			id = strings.TrimSpace(id)
+			for _, number := range []int{i + 1, i + 2} {
+				if suppressed[number] == nil {
+					suppressed[number] = make(map[string]bool)
+				}
+				suppressed[number][id] = true
+			}
+		}
+	}
+	return suppressed
+}
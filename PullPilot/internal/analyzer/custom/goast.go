@@ -0,0 +1,339 @@
+package custom
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// GoCheckNames are the structural Go checks, enabled through config.GoChecks.
+var GoCheckNames = []string{"ignored-error", "context-background", "loop-var-capture", "log-fatal", "exported-doc"}
+
+// goChecker runs the enabled structural checks on one parsed Go file and reports issues on
+// added lines only.
+type goChecker struct {
+	fset        *token.FileSet
+	file        *models.File
+	ast         *ast.File
+	added       map[int]string
+	suppressed  map[int]map[string]bool
+	enabled     map[string]bool
+	errorFuncs  map[string]bool
+	loopVarsFix bool // The module uses Go 1.22+, where each iteration has its own loop variables
+	issues      []*models.Issue
+}
+
+// checkGoFiles parses the changed Go files and runs the enabled checks on them.
+func (r *Rules) checkGoFiles(files []*models.File, checkoutDir string) []*models.Issue {
+	enabled := make(map[string]bool)
+	for _, name := range r.cfg.GoChecks {
+		if !containsString(GoCheckNames, name) {
+			log.Printf("Warning: Unknown Go check %q, expected one of %v", name, GoCheckNames)
+		}
+		enabled[name] = true
+	}
+	if len(enabled) == 0 {
+		return nil
+	}
+	errorFuncs := make(map[string]bool)
+	for _, name := range r.cfg.GoIgnoredErrorFuncs {
+		errorFuncs[name] = true
+	}
+	loopVarsFix := moduleGoVersionAtLeast(checkoutDir, 22)
+
+	var issues []*models.Issue
+	for _, file := range files {
+		if !strings.HasSuffix(file.Path, ".go") || file.Status == "removed" {
+			continue
+		}
+		added := addedLines(file)
+		if len(added) == 0 {
+			continue
+		}
+
+		fset := token.NewFileSet()
+		parsed, err := parser.ParseFile(fset, file.Path, file.Content, parser.ParseComments)
+		if err != nil {
+			log.Printf("Skipping Go checks for %s: %v", file.Path, err)
+			continue
+		}
+
+		checker := &goChecker{
+			fset:        fset,
+			file:        file,
+			ast:         parsed,
+			added:       added,
+			suppressed:  suppressions(file.Content),
+			enabled:     enabled,
+			errorFuncs:  errorFuncs,
+			loopVarsFix: loopVarsFix,
+		}
+		checker.run()
+		issues = append(issues, checker.issues...)
+	}
+	return issues
+}
+
+func (c *goChecker) run() {
+	isMain := c.ast.Name.Name == "main"
+	isTest := strings.HasSuffix(c.file.Path, "_test.go")
+
+	for _, decl := range c.ast.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if c.enabled["exported-doc"] && !isTest {
+			c.checkDoc(fn)
+		}
+		if fn.Body == nil {
+			continue
+		}
+		if c.enabled["context-background"] {
+			c.checkHandlerContexts(fn.Type, fn.Body)
+		}
+
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.ExprStmt:
+				if c.enabled["ignored-error"] {
+					if call, ok := node.X.(*ast.CallExpr); ok {
+						c.checkIgnoredError(call, "discarded")
+					}
+				}
+			case *ast.AssignStmt:
+				if c.enabled["ignored-error"] && len(node.Rhs) == 1 && isBlank(node.Lhs[len(node.Lhs)-1]) {
+					if call, ok := node.Rhs[0].(*ast.CallExpr); ok {
+						c.checkIgnoredError(call, "assigned to _")
+					}
+				}
+			case *ast.CallExpr:
+				if c.enabled["log-fatal"] && !isMain {
+					c.checkLogFatal(node)
+				}
+			case *ast.RangeStmt:
+				if c.enabled["loop-var-capture"] && !c.loopVarsFix {
+					c.checkLoopCapture(node.Body, node.Key, node.Value)
+				}
+			case *ast.ForStmt:
+				if c.enabled["loop-var-capture"] && !c.loopVarsFix {
+					if init, ok := node.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+						c.checkLoopCapture(node.Body, init.Lhs...)
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+func (c *goChecker) report(node ast.Node, check string, severity models.Severity, title, description, suggestion string) {
+	line := c.fset.Position(node.Pos()).Line
+	if _, ok := c.added[line]; !ok || c.suppressed[line][check] {
+		return
+	}
+	c.issues = append(c.issues, &models.Issue{
+		Path:        c.file.Path,
+		Line:        line,
+		Title:       title,
+		Description: description,
+		Severity:    severity,
+		Suggestion:  suggestion,
+		Source:      "go-ast",
+		Category:    check,
+	})
+}
+
+// checkIgnoredError flags calls to the configured functions whose error result is dropped.
+// Without type information the error is assumed to be the last result.
+func (c *goChecker) checkIgnoredError(call *ast.CallExpr, how string) {
+	name := calleeName(call.Fun)
+	if name == "" || !c.errorFuncs[name] {
+		return
+	}
+	c.report(call, "ignored-error", models.SeverityWarning, "Ignored Error",
+		fmt.Sprintf("The error returned by `%s` is %s.", name, how),
+		"Handle or return the error; if it truly cannot fail here, say why in a comment.")
+}
+
+// checkHandlerContexts flags context.Background() and context.TODO() in functions, including
+// closures, that receive an *http.Request or a framework context carrying the request's.
+func (c *goChecker) checkHandlerContexts(fnType *ast.FuncType, body *ast.BlockStmt) {
+	request := handlerRequest(fnType)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			if handlerRequest(node.Type) != "" {
+				c.checkHandlerContexts(node.Type, node.Body)
+				return false
+			}
+		case *ast.CallExpr:
+			name := calleeName(node.Fun)
+			if request != "" && (name == "context.Background" || name == "context.TODO") {
+				c.report(node, "context-background", models.SeverityWarning, "Request Context Dropped",
+					fmt.Sprintf("`%s()` inside a request handler loses the request's deadline and cancellation.", name),
+					fmt.Sprintf("Derive the context from the request, e.g. `%s`.", request))
+			}
+		}
+		return true
+	})
+}
+
+// handlerRequest returns how a handler with this signature reaches its request context, or ""
+// if it is not a handler.
+func handlerRequest(fnType *ast.FuncType) string {
+	if fnType.Params == nil {
+		return ""
+	}
+	for _, field := range fnType.Params.List {
+		name := "r"
+		if len(field.Names) > 0 {
+			name = field.Names[0].Name
+		}
+		switch typeName(field.Type) {
+		case "*http.Request":
+			return name + ".Context()"
+		case "*gin.Context":
+			return name + ".Request.Context()"
+		case "echo.Context":
+			return name + ".Request().Context()"
+		}
+	}
+	return ""
+}
+
+func (c *goChecker) checkLogFatal(call *ast.CallExpr) {
+	switch name := calleeName(call.Fun); name {
+	case "log.Fatal", "log.Fatalf", "log.Fatalln":
+		c.report(call, "log-fatal", models.SeverityWarning, "log.Fatal Outside main",
+			fmt.Sprintf("`%s` exits the process without running deferred calls, which library code should leave to its caller.", name),
+			"Return an error and let main decide how to exit.")
+	}
+}
+
+// checkLoopCapture flags goroutines in a loop body whose closure refers to the loop
+// variables, which before Go 1.22 are shared by all iterations.
+func (c *goChecker) checkLoopCapture(body *ast.BlockStmt, vars ...ast.Expr) {
+	objects := make(map[*ast.Object]string)
+	for _, v := range vars {
+		if ident, ok := v.(*ast.Ident); ok && ident.Obj != nil && ident.Name != "_" {
+			objects[ident.Obj] = ident.Name
+		}
+	}
+	if len(objects) == 0 {
+		return
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		stmt, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		lit, ok := stmt.Call.Fun.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		captured := make(map[string]bool)
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && ident.Obj != nil {
+				if name, ok := objects[ident.Obj]; ok && !captured[name] {
+					captured[name] = true
+					c.report(stmt, "loop-var-capture", models.SeverityError, "Goroutine Captures Loop Variable",
+						fmt.Sprintf("The goroutine uses the loop variable `%s`, which is shared by every iteration before Go 1.22, so it may see a later value.", name),
+						fmt.Sprintf("Pass `%s` to the function literal as an argument, or copy it with `%s := %s` before the go statement.", name, name, name))
+				}
+			}
+			return true
+		})
+		return true
+	})
+}
+
+func (c *goChecker) checkDoc(fn *ast.FuncDecl) {
+	if !fn.Name.IsExported() || fn.Doc != nil {
+		return
+	}
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		receiver := strings.TrimPrefix(typeName(fn.Recv.List[0].Type), "*")
+		if receiver == "" || !ast.IsExported(strings.SplitN(receiver, "[", 2)[0]) {
+			return
+		}
+	}
+	c.report(fn, "exported-doc", models.SeverityInfo, "Missing Doc Comment",
+		fmt.Sprintf("Exported function `%s` has no doc comment.", fn.Name.Name),
+		fmt.Sprintf("Add a comment starting with `// %s` that explains what it does.", fn.Name.Name))
+}
+
+// calleeName returns the dotted name of a called function, such as "os.Remove" or
+// "base64.StdEncoding.DecodeString", or "" when it is not a plain name.
+func calleeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		if x := calleeName(e.X); x != "" {
+			return x + "." + e.Sel.Name
+		}
+	}
+	return ""
+}
+
+// typeName renders simple type expressions such as "*http.Request".
+func typeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		if name := typeName(e.X); name != "" {
+			return "*" + name
+		}
+	case *ast.IndexExpr:
+		return typeName(e.X)
+	case *ast.IndexListExpr:
+		return typeName(e.X)
+	default:
+		return calleeName(expr)
+	}
+	return ""
+}
+
+func isBlank(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "_"
+}
+
+var goDirectiveRegex = regexp.MustCompile(`(?m)^go\s+1\.(\d+)`)
+
+// moduleGoVersionAtLeast reports whether the go.mod in dir declares go 1.minor or later. With
+// no checkout there is no go.mod to read; the working directory is PullPilot's own module.
+func moduleGoVersionAtLeast(dir string, minor int) bool {
+	if dir == "" {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return false
+	}
+	match := goDirectiveRegex.FindSubmatch(data)
+	if match == nil {
+		return false
+	}
+	version, err := strconv.Atoi(string(match[1]))
+	return err == nil && version >= minor
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
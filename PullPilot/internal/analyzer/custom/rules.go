@@ -2,6 +2,7 @@ package custom
 
 import (
 	"context"
+	"log"
 
 	"github.com/keploy/PullPilot/internal/config"
 	"github.com/keploy/PullPilot/pkg/models"
@@ -17,8 +18,33 @@ func NewRules(cfg *config.Config) *Rules {
 	}
 }
 
-func (r *Rules) Analyze(ctx context.Context, files []*models.File) ([]*models.Issue, error) {
+// Analyze checks files against the built-in and repository rules. checkoutDir is the local
+// checkout of the repository under review, or "" when there is none.
+func (r *Rules) Analyze(ctx context.Context, checkoutDir string, files []*models.File) ([]*models.Issue, error) {
 	var issues []*models.Issue
 
+	if r.cfg.EnableSecretScan {
+		// The allowlist lives in the checkout, so it is re-read for every run.
+		allowlist, err := LoadAllowlist(r.cfg.SecretsAllowlistFile(checkoutDir))
+		if err != nil {
+			log.Printf("Warning: %v, scanning without an allowlist", err)
+		}
+		issues = append(issues, scanSecrets(files, allowlist)...)
+	}
+
+	issues = append(issues, r.checkGoFiles(files, checkoutDir)...)
+
+	// Rules come from .pullpilot.yml in the checkout and may change between runs.
+	repoConfig, err := config.LoadRepoConfig(r.cfg.RepoConfigFile(checkoutDir))
+	if err != nil {
+		log.Printf("Warning: Skipping custom rules: %v", err)
+		return issues, nil
+	}
+	rules, errs := compileRules(repoConfig.Rules)
+	for _, err := range errs {
+		log.Printf("Warning: Skipping custom rule: %v", err)
+	}
+	issues = append(issues, evaluateRules(rules, files)...)
+
 	return issues, nil
 }
@@ -0,0 +1,271 @@
+package custom
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/internal/config"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const CategorySecret = "secret"
+
+// secretDetector finds one kind of credential. The secret is the capture group named
+// "secret" when the pattern has one, otherwise the whole match.
+type secretDetector struct {
+	name    string
+	pattern *regexp.Regexp
+	// minEntropy rejects low-entropy matches such as "password = 'changeme_changeme'". It is
+	// lowered for hex and short values, see entropyThreshold.
+	minEntropy float64
+}
+
+var secretDetectors = []secretDetector{
+	{name: "AWS access key ID", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`)},
+	{name: "AWS secret access key", pattern: regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private).{0,20}?[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`), minEntropy: 3.5},
+	{name: "Google Cloud service account key", pattern: regexp.MustCompile(`"private_key_id"\s*:\s*"(?P<secret>[a-f0-9]{40})"`)},
+	{name: "Google API key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{name: "Azure storage account key", pattern: regexp.MustCompile(`(?i)AccountKey=(?P<secret>[A-Za-z0-9+/]{86}==)`)},
+	{name: "Azure client secret", pattern: regexp.MustCompile(`(?i)(?:azure|client).{0,20}?secret.{0,10}?[:=]\s*["']?(?P<secret>[A-Za-z0-9_~.\-]{3}\dQ~[A-Za-z0-9_~.\-]{31,34})`)},
+	{name: "GitHub token", pattern: regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b`)},
+	{name: "GitHub fine-grained token", pattern: regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{82}\b`)},
+	{name: "private key", pattern: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{name: "JSON Web Token", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+}
+
+// genericSecret catches credentials assigned to suspiciously named variables. It only fires on
+// high-entropy values and on lines none of the specific detectors matched.
+var genericSecret = secretDetector{
+	name:       "hard-coded credential",
+	pattern:    regexp.MustCompile(`(?i)(?:secret|token|passw(?:or)?d|api[_-]?key|access[_-]?key|credential)\w*["']?\s*(?::=|=|:)\s*["'](?P<secret>[A-Za-z0-9+/=_\-.]{16,})["']`),
+	minEntropy: 3.5,
+}
+
+const (
+	// hexMinEntropy is the bar for hex values, which carry at most 4 bits per character.
+	hexMinEntropy = 3.0
+	// maxEntropyRatio caps the bar at this share of the most entropy a value's charset and
+	// length allow, so that short random tokens still qualify.
+	maxEntropyRatio = 0.85
+)
+
+// secretFinding is a detected secret on an added line.
+type secretFinding struct {
+	detector string
+	line     int
+	secret   string
+}
+
+// scanSecrets looks for credentials on the lines a PR adds. Added files without a patch are
+// scanned in full.
+func scanSecrets(files []*models.File, allowlist *Allowlist) []*models.Issue {
+	var issues []*models.Issue
+	for _, file := range files {
+		if allowlist.AllowsPath(file.Path) {
+			continue
+		}
+
+		for _, finding := range findSecrets(addedLines(file)) {
+			if allowlist.AllowsSecret(finding.secret) {
+				continue
+			}
+			issues = append(issues, &models.Issue{
+				Path:     file.Path,
+				Line:     finding.line,
+				Title:    "Hard-coded Secret",
+				Severity: models.SeverityError,
+				Description: fmt.Sprintf("Possible %s `%s` added in this change. Anything committed stays in the git history, even once removed.",
+					finding.detector, redact(finding.secret)),
+				Suggestion: "Revoke and rotate the secret, then load it from the environment or a secret manager. Known test fixtures can be added to the secrets allowlist.",
+				Source:     "secret-scan",
+				Category:   CategorySecret,
+			})
+		}
+	}
+	return issues
+}
+
+// addedLines returns the lines a file adds keyed by line number.
+func addedLines(file *models.File) map[int]string {
+	if file.Patch == "" && file.Status == "added" {
+		lines := make(map[int]string)
+		for i, line := range strings.Split(file.Content, "\n") {
+			lines[i+1] = line
+		}
+		return lines
+	}
+	return diff.AddedLines(file.Patch)
+}
+
+// sortedLines returns the line numbers of lines in ascending order.
+func sortedLines(lines map[int]string) []int {
+	numbers := make([]int, 0, len(lines))
+	for number := range lines {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+func findSecrets(lines map[int]string) []secretFinding {
+	var findings []secretFinding
+	for _, number := range sortedLines(lines) {
+		matched := false
+		for _, detector := range secretDetectors {
+			for _, secret := range detector.find(lines[number]) {
+				findings = append(findings, secretFinding{detector: detector.name, line: number, secret: secret})
+				matched = true
+			}
+		}
+		if matched {
+			continue
+		}
+		for _, secret := range genericSecret.find(lines[number]) {
+			findings = append(findings, secretFinding{detector: genericSecret.name, line: number, secret: secret})
+		}
+	}
+	return findings
+}
+
+func (d secretDetector) find(line string) []string {
+	group := d.pattern.SubexpIndex("secret")
+
+	var secrets []string
+	for _, match := range d.pattern.FindAllStringSubmatch(line, -1) {
+		secret := match[0]
+		if group > 0 {
+			secret = match[group]
+		}
+		if d.minEntropy > 0 && shannonEntropy(secret) < entropyThreshold(secret, d.minEntropy) {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets
+}
+
+// entropyThreshold scales min to the value, as gitleaks and truffleHog do: a value of n
+// characters from an alphabet of k symbols has at most log2(min(n, k)) bits per character, so
+// a fixed threshold would never flag hex keys or short tokens.
+func entropyThreshold(value string, min float64) float64 {
+	alphabet := 64.0
+	if isHex(value) {
+		alphabet = 16
+		min = math.Min(min, hexMinEntropy)
+	}
+	ceiling := math.Log2(math.Min(float64(len(value)), alphabet))
+	return math.Min(min, maxEntropyRatio*ceiling)
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// shannonEntropy is the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	length := float64(len([]rune(s)))
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// redact keeps enough of a secret to recognise it in review without making it usable.
+func redact(secret string) string {
+	if strings.HasPrefix(secret, "-----BEGIN") {
+		return secret
+	}
+	keep := 4
+	if len(secret) < 12 {
+		keep = 0
+	}
+	return secret[:keep] + strings.Repeat("*", 8) + fmt.Sprintf(" (%d chars)", len(secret))
+}
+
+// Allowlist suppresses secret findings for known test fixtures. Each line of the file is a
+// path glob (matched with config.MatchGlob), or "secret:" followed by a regular expression
+// matched against the detected value. Blank lines and lines starting with # are ignored.
+type Allowlist struct {
+	paths   []string
+	secrets []*regexp.Regexp
+}
+
+// LoadAllowlist reads an allowlist file. A missing file is an empty allowlist.
+func LoadAllowlist(path string) (*Allowlist, error) {
+	allowlist := &Allowlist{}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return allowlist, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secrets allowlist: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for number := 1; scanner.Scan(); number++ {
+		entry := strings.TrimSpace(scanner.Text())
+		switch {
+		case entry == "" || strings.HasPrefix(entry, "#"):
+		case strings.HasPrefix(entry, "secret:"):
+			pattern, err := regexp.Compile(strings.TrimSpace(strings.TrimPrefix(entry, "secret:")))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid secret pattern: %w", path, number, err)
+			}
+			allowlist.secrets = append(allowlist.secrets, pattern)
+		default:
+			allowlist.paths = append(allowlist.paths, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read secrets allowlist: %w", err)
+	}
+	return allowlist, nil
+}
+
+func (a *Allowlist) AllowsPath(path string) bool {
+	if a == nil {
+		return false
+	}
+	for _, pattern := range a.paths {
+		if config.MatchGlob(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Allowlist) AllowsSecret(secret string) bool {
+	if a == nil {
+		return false
+	}
+	for _, pattern := range a.secrets {
+		if pattern.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,456 @@
+package custom
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Structural rules are semgrep-style patterns such as `$X.Query("..." + $Y)`, matched against
+// syntax trees: go/ast for Go and tree-sitter grammars for Python, Java, JavaScript and
+// TypeScript. The pattern is parsed with the grammar of the file it is checked against, so
+// operator precedence is the language's own and whitespace, comments and line breaks do not
+// matter.
+//
+// Pattern syntax:
+//   - $NAME    a metavariable: any one expression or other syntax node, such as `db`, `s.db`
+//              or `ids[0]`. Repeated uses of the same name must match the same code.
+//   - ...      any sequence of arguments, elements or statements, including none.
+//   - "..."    any string literal.
+//   - anything else must match node for node.
+
+// syntaxNode is a node of a parsed file or pattern, whichever parser produced it.
+type syntaxNode struct {
+	kind       string // Node type; for Go it includes the operator, as in "*ast.BinaryExpr +"
+	text       string // Leaves only: the token, or the quoted content of a string literal
+	leaf       bool
+	str        bool // A string literal, compared by content whatever its quotes
+	bindable   bool // Can be matched by a metavariable
+	start, end int  // Byte offsets in the source
+	line       int
+	children   []*syntaxNode
+}
+
+// Patterns are parsed as code of the target language with metavariables and ellipses replaced
+// by these identifiers.
+const (
+	metavariablePrefix = "__pp_mv_"
+	ellipsisIdent      = "__pp_ellipsis"
+)
+
+// structuralLanguages are the grammars patterns are compiled for. TSX is separate from
+// TypeScript because the two parse type assertions differently.
+var structuralLanguages = []string{"go", "python", "java", "javascript", "typescript", "tsx"}
+
+var structuralLanguageByExtension = map[string]string{
+	".go":   "go",
+	".py":   "python",
+	".java": "java",
+	".js":   "javascript", ".jsx": "javascript", ".mjs": "javascript", ".cjs": "javascript",
+	".ts":  "typescript",
+	".tsx": "tsx",
+}
+
+type patternKind int
+
+const (
+	patternLiteral patternKind = iota
+	patternMeta
+	patternEllipsis
+	patternAnyString
+)
+
+type patternNode struct {
+	kind     patternKind
+	node     *syntaxNode // Literal nodes
+	name     string      // Metavariable name, such as $X
+	children []*patternNode
+}
+
+// structuralPattern is a compiled structural rule pattern: a sequence of statements or a single
+// expression per language.
+type structuralPattern struct {
+	byLanguage map[string][]*patternNode
+}
+
+// compileStructural parses a pattern for every language it applies to. Rule languages are the
+// names accepted in rule configs; with none the pattern is compiled for every language it
+// parses in, and must parse in at least one.
+func compileStructural(pattern string, languages []string) (*structuralPattern, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, errors.New("structural pattern is empty")
+	}
+
+	targets := structuralLanguages
+	if len(languages) > 0 {
+		targets = nil
+		for _, language := range languages {
+			switch language = strings.ToLower(language); language {
+			case "typescript":
+				targets = append(targets, "typescript", "tsx")
+			default:
+				targets = append(targets, language)
+			}
+		}
+	}
+
+	prepared := preparePattern(pattern)
+	compiled := &structuralPattern{byLanguage: make(map[string][]*patternNode)}
+	for _, language := range targets {
+		nodes, err := parsePattern(language, prepared)
+		if err != nil {
+			if len(languages) > 0 {
+				return nil, fmt.Errorf("structural pattern is not valid %s: %w", language, err)
+			}
+			continue
+		}
+		// Matches are searched for anywhere in a sequence, so ellipses around the whole pattern
+		// add nothing, and would only widen the match.
+		pattern := toPatterns(unwrapStatement(nodes))
+		for len(pattern) > 0 && pattern[0].kind == patternEllipsis {
+			pattern = pattern[1:]
+		}
+		for len(pattern) > 0 && pattern[len(pattern)-1].kind == patternEllipsis {
+			pattern = pattern[:len(pattern)-1]
+		}
+		if len(pattern) == 0 || len(pattern) == 1 && pattern[0].kind == patternMeta {
+			return nil, errors.New("structural pattern matches everything")
+		}
+		compiled.byLanguage[language] = pattern
+	}
+	if len(compiled.byLanguage) == 0 {
+		return nil, errors.New("structural pattern does not parse in any supported language")
+	}
+	return compiled, nil
+}
+
+func parsePattern(language, pattern string) ([]*syntaxNode, error) {
+	if language == "go" {
+		return goPatternNodes(pattern)
+	}
+	return treeSitterPatternNodes(language, pattern)
+}
+
+// preparePattern replaces metavariables and ellipses outside string literals with identifiers,
+// so that the pattern parses as ordinary code. An ellipsis right after an operand or right
+// before one is Go's variadic `xs...` or JavaScript's spread `...xs`, and is left alone.
+func preparePattern(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			end := quotedEnd(pattern, i)
+			b.WriteString(pattern[i:end])
+			i = end
+		case c == '$' && i+1 < len(pattern) && (pattern[i+1] == '_' || pattern[i+1] >= 'A' && pattern[i+1] <= 'Z'):
+			end := i + 1
+			for end < len(pattern) && (pattern[end] == '_' || pattern[end] >= 'A' && pattern[end] <= 'Z' || isDigit(pattern[end])) {
+				end++
+			}
+			b.WriteString(metavariablePrefix + pattern[i+1:end])
+			i = end
+		case strings.HasPrefix(pattern[i:], "...") && standaloneEllipsis(pattern, i):
+			b.WriteString(ellipsisIdent)
+			i += 3
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func standaloneEllipsis(s string, i int) bool {
+	before := strings.TrimRight(s[:i], " \t")
+	if before != "" {
+		if c := before[len(before)-1]; isWordChar(c) || c == ')' || c == ']' {
+			return false
+		}
+	}
+	if after := s[i+3:]; after != "" {
+		if c := after[0]; isWordChar(c) || c == '$' || c == '(' || c == '[' || c == '{' {
+			return false
+		}
+	}
+	return true
+}
+
+// quotedEnd returns the offset just past the string literal starting at s[start].
+func quotedEnd(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// unwrapStatement turns a pattern that is a single expression statement into the expression,
+// so that `$X.Query(...)` also matches calls inside assignments and return statements.
+func unwrapStatement(nodes []*syntaxNode) []*syntaxNode {
+	if len(nodes) == 1 && statementWrappers[nodes[0].kind] && len(nodes[0].children) > 0 {
+		return nodes[0].children[:1]
+	}
+	return nodes
+}
+
+// statementWrappers are nodes that only wrap an expression or a parameter. One that wraps
+// nothing but a placeholder, such as the statement around a lone `...`, stands for the
+// placeholder itself.
+var statementWrappers = map[string]bool{
+	"*ast.ExprStmt":        true,
+	"*ast.Field":           true,
+	"expression_statement": true,
+}
+
+func toPatterns(nodes []*syntaxNode) []*patternNode {
+	patterns := make([]*patternNode, 0, len(nodes))
+	for _, n := range nodes {
+		patterns = append(patterns, toPattern(n))
+	}
+	return patterns
+}
+
+func toPattern(n *syntaxNode) *patternNode {
+	switch {
+	case n.leaf && n.text == ellipsisIdent:
+		return &patternNode{kind: patternEllipsis}
+	case n.leaf && strings.HasPrefix(n.text, metavariablePrefix):
+		return &patternNode{kind: patternMeta, name: "$" + strings.TrimPrefix(n.text, metavariablePrefix)}
+	case n.str && n.text == `"..."`:
+		return &patternNode{kind: patternAnyString}
+	}
+
+	p := &patternNode{kind: patternLiteral, node: n, children: toPatterns(n.children)}
+	if statementWrappers[n.kind] {
+		var significant []*patternNode
+		for _, child := range p.children {
+			if child.kind != patternLiteral || !child.node.leaf || child.node.text != ";" {
+				significant = append(significant, child)
+			}
+		}
+		if len(significant) == 1 && (significant[0].kind == patternEllipsis || significant[0].kind == patternMeta) {
+			return significant[0]
+		}
+	}
+	return p
+}
+
+// stringContent strips the prefix and quotes of a string literal.
+func stringContent(literal string) string {
+	literal = strings.TrimLeft(literal, "rRbBfFuU")
+	for _, quote := range []string{`"""`, `'''`, `"`, `'`, "`"} {
+		if len(literal) >= 2*len(quote) && strings.HasPrefix(literal, quote) && strings.HasSuffix(literal, quote) {
+			return literal[len(quote) : len(literal)-len(quote)]
+		}
+	}
+	return literal
+}
+
+// normalizeString makes string literals compare equal regardless of their quotes.
+func normalizeString(literal string) string {
+	return `"` + stringContent(literal) + `"`
+}
+
+// structuralMatch is one match of a pattern in a file.
+type structuralMatch struct {
+	line       int
+	start, end int
+	bindings   map[string]binding
+}
+
+// binding is the code a metavariable matched. Uses of the same metavariable compare keys,
+// which ignore formatting; the offsets locate the original source.
+type binding struct {
+	key        string
+	start, end int
+}
+
+// findAll matches the pattern against every sequence of sibling nodes in the tree. Only the
+// first match starting on a line is kept.
+func (p *structuralPattern) findAll(language string, root *syntaxNode) []structuralMatch {
+	pattern, ok := p.byLanguage[language]
+	if !ok || root == nil {
+		return nil
+	}
+
+	var matches []structuralMatch
+	seen := make(map[int]bool)
+
+	var walk func(n *syntaxNode)
+	walk = func(n *syntaxNode) {
+		for i, child := range n.children {
+			if end, bindings, ok := matchSequence(pattern, n.children, i, map[string]binding{}, false); ok && end > i {
+				if !seen[child.line] {
+					seen[child.line] = true
+					matches = append(matches, structuralMatch{line: child.line, start: child.start, end: n.children[end-1].end, bindings: bindings})
+				}
+			}
+			walk(child)
+		}
+	}
+	walk(root)
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	return matches
+}
+
+// matchSequence matches pattern against src starting at index i. With whole set the pattern
+// must consume src up to its end, as it must for the children of a node. It returns the index
+// after the match and the metavariable bindings.
+func matchSequence(pattern []*patternNode, src []*syntaxNode, i int, bindings map[string]binding, whole bool) (int, map[string]binding, bool) {
+	if len(pattern) == 0 {
+		if whole && i != len(src) {
+			return 0, nil, false
+		}
+		return i, bindings, true
+	}
+
+	p, rest := pattern[0], pattern[1:]
+	if p.kind == patternEllipsis {
+		for k := i; k <= len(src); k++ {
+			if end, b, ok := matchSequence(rest, src, k, bindings, whole); ok {
+				return end, b, true
+			}
+		}
+		// As in semgrep, `..., $X` also matches when nothing precedes $X.
+		if len(rest) > 0 && isComma(rest[0]) {
+			return matchSequence(rest[1:], src, i, bindings, whole)
+		}
+		return 0, nil, false
+	}
+
+	if i < len(src) {
+		if b, ok := matchNode(p, src[i], bindings); ok {
+			if end, b, ok := matchSequence(rest, src, i+1, b, whole); ok {
+				return end, b, true
+			}
+		}
+	}
+	// And `$X, ...` also matches when nothing follows $X.
+	if isComma(p) && len(rest) > 0 && rest[0].kind == patternEllipsis {
+		return matchSequence(rest[1:], src, i, bindings, whole)
+	}
+	return 0, nil, false
+}
+
+func isComma(p *patternNode) bool {
+	return p.kind == patternLiteral && p.node.leaf && p.node.text == ","
+}
+
+func matchNode(p *patternNode, n *syntaxNode, bindings map[string]binding) (map[string]binding, bool) {
+	switch p.kind {
+	case patternMeta:
+		if !n.bindable {
+			return nil, false
+		}
+		key := nodeKey(n)
+		if bound, ok := bindings[p.name]; ok {
+			return bindings, bound.key == key
+		}
+		next := copyBindings(bindings)
+		next[p.name] = binding{key: key, start: n.start, end: n.end}
+		return next, true
+	case patternAnyString:
+		return bindings, n.str
+	case patternEllipsis:
+		return bindings, true
+	}
+
+	if p.node.kind != n.kind || p.node.leaf != n.leaf {
+		return nil, false
+	}
+	if n.leaf {
+		return bindings, p.node.text == n.text
+	}
+	_, b, ok := matchSequence(p.children, n.children, 0, bindings, true)
+	return b, ok
+}
+
+// nodeKey renders a node with its type and structure, so that two nodes have the same key
+// when they are the same code however it is formatted.
+func nodeKey(n *syntaxNode) string {
+	var b strings.Builder
+	var render func(n *syntaxNode)
+	render = func(n *syntaxNode) {
+		b.WriteString("(" + n.kind)
+		if n.leaf {
+			b.WriteString(" " + strconv.Quote(n.text))
+		}
+		for _, child := range n.children {
+			b.WriteByte(' ')
+			render(child)
+		}
+		b.WriteByte(')')
+	}
+	render(n)
+	return b.String()
+}
+
+func copyBindings(bindings map[string]binding) map[string]binding {
+	next := make(map[string]binding, len(bindings)+1)
+	for k, v := range bindings {
+		next[k] = v
+	}
+	return next
+}
+
+// parseSource parses a file for structural matching. It returns the language of the file, or
+// false for unsupported languages and files that do not parse.
+func parseSource(path, content string) (string, *syntaxNode, bool) {
+	language, ok := structuralLanguageByExtension[filepath.Ext(path)]
+	if !ok {
+		return "", nil, false
+	}
+	var root *syntaxNode
+	var err error
+	if language == "go" {
+		root, err = goSyntaxTree(path, content)
+	} else {
+		root, err = treeSitterSyntaxTree(language, content)
+	}
+	if err != nil {
+		return "", nil, false
+	}
+	return language, root, true
+}
+
+// sourceSnippet is a range of source collapsed onto one line and shortened for a comment.
+func sourceSnippet(content string, start, end int) string {
+	snippet := strings.Join(strings.Fields(content[start:end]), " ")
+	if len(snippet) > 120 {
+		snippet = snippet[:117] + "..."
+	}
+	return snippet
+}
+
+// expandMetavariables replaces $NAME in a rule message with the code it matched.
+func expandMetavariables(message, content string, bindings map[string]binding) string {
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	// Longest first, so that $XY is not replaced as $X followed by Y.
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, name := range names {
+		b := bindings[name]
+		message = strings.ReplaceAll(message, name, sourceSnippet(content, b.start, b.end))
+	}
+	return message
+}
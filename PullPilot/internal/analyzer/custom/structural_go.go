@@ -0,0 +1,134 @@
+package custom
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// goSyntaxTree parses a Go file for structural matching. Comments are not part of the tree.
+func goSyntaxTree(path, content string) (*syntaxNode, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	return goNode(fset.File(file.Pos()), file), nil
+}
+
+// goPatternWrappers place a pattern where Go allows statements, expressions or declarations.
+var goPatternWrappers = []string{
+	"package _\nfunc _() {\n%s\n}",
+	"package _\nvar _ = %s",
+	"package _\n%s",
+}
+
+// goPatternNodes parses a prepared pattern as Go statements, an expression or declarations,
+// whichever works first.
+func goPatternNodes(pattern string) ([]*syntaxNode, error) {
+	var firstErr error
+	for i, wrapper := range goPatternWrappers {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "pattern.go", fmt.Sprintf(wrapper, pattern), parser.SkipObjectResolution)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		tokFile := fset.File(file.Pos())
+
+		var nodes []ast.Node
+		switch i {
+		case 0:
+			for _, stmt := range file.Decls[0].(*ast.FuncDecl).Body.List {
+				nodes = append(nodes, stmt)
+			}
+		case 1:
+			for _, value := range file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values {
+				nodes = append(nodes, value)
+			}
+		default:
+			for _, decl := range file.Decls {
+				nodes = append(nodes, decl)
+			}
+		}
+		if len(nodes) == 0 {
+			return nil, errors.New("pattern has no code")
+		}
+
+		converted := make([]*syntaxNode, 0, len(nodes))
+		for _, n := range nodes {
+			converted = append(converted, goNode(tokFile, n))
+		}
+		return converted, nil
+	}
+	return nil, firstErr
+}
+
+func goNode(file *token.File, n ast.Node) *syntaxNode {
+	node := &syntaxNode{kind: goKind(n), start: goOffset(file, n.Pos()), end: goOffset(file, n.End())}
+	if n.Pos().IsValid() {
+		node.line = file.Line(n.Pos())
+	}
+	_, node.bindable = n.(ast.Expr)
+
+	switch n := n.(type) {
+	case *ast.Ident:
+		node.leaf, node.text = true, n.Name
+		return node
+	case *ast.BasicLit:
+		node.leaf, node.text = true, n.Value
+		if n.Kind == token.STRING {
+			node.str, node.text = true, normalizeString(n.Value)
+		}
+		return node
+	}
+
+	ast.Inspect(n, func(child ast.Node) bool {
+		switch child.(type) {
+		case nil, *ast.CommentGroup, *ast.Comment:
+			return false
+		}
+		if child == n {
+			return true
+		}
+		node.children = append(node.children, goNode(file, child))
+		return false
+	})
+	return node
+}
+
+// goKind names a node type, with the operator or keyword for nodes that are otherwise the same
+// type, such as a + b and a - b or x = y and x := y.
+func goKind(n ast.Node) string {
+	kind := fmt.Sprintf("%T", n)
+	switch n := n.(type) {
+	case *ast.BinaryExpr:
+		return kind + " " + n.Op.String()
+	case *ast.UnaryExpr:
+		return kind + " " + n.Op.String()
+	case *ast.AssignStmt:
+		return kind + " " + n.Tok.String()
+	case *ast.IncDecStmt:
+		return kind + " " + n.Tok.String()
+	case *ast.BranchStmt:
+		return kind + " " + n.Tok.String()
+	case *ast.GenDecl:
+		return kind + " " + n.Tok.String()
+	case *ast.RangeStmt:
+		return kind + " " + n.Tok.String()
+	case *ast.ChanType:
+		return fmt.Sprintf("%s %d", kind, n.Dir)
+	}
+	return kind
+}
+
+func goOffset(file *token.File, pos token.Pos) int {
+	if !pos.IsValid() || int(pos) > file.Base()+file.Size() {
+		return 0
+	}
+	return file.Offset(pos)
+}
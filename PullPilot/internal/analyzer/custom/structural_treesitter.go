@@ -0,0 +1,166 @@
+package custom
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tsjava "github.com/tree-sitter/tree-sitter-java/bindings/go"
+	tsjavascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
+	tspython "github.com/tree-sitter/tree-sitter-python/bindings/go"
+	tstypescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+)
+
+// treeSitterGrammar is how structural rules parse one language with tree-sitter.
+type treeSitterGrammar struct {
+	language *sitter.Language
+	strings  map[string]bool // Node types of string literals, matched as a whole
+	// wrappers place a pattern where the language allows it; body is the node type whose
+	// children are the pattern, the root when empty.
+	wrappers []patternWrapper
+	// terminateEllipses adds the semicolon a `...` statement needs to parse.
+	terminateEllipses bool
+}
+
+// ellipsisStatementRegex matches a line that is only an ellipsis placeholder.
+var ellipsisStatementRegex = regexp.MustCompile(`(?m)^(\s*` + ellipsisIdent + `)\s*$`)
+
+type patternWrapper struct {
+	format string
+	body   string
+}
+
+var (
+	jsStrings = map[string]bool{"string": true, "template_string": true}
+	jsWrapper = []patternWrapper{{format: "%s"}}
+)
+
+var treeSitterGrammars = map[string]*treeSitterGrammar{
+	"python": {
+		language: sitter.NewLanguage(tspython.Language()),
+		strings:  map[string]bool{"string": true},
+		wrappers: []patternWrapper{{format: "%s"}},
+	},
+	"java": {
+		language: sitter.NewLanguage(tsjava.Language()),
+		strings:  map[string]bool{"string_literal": true, "text_block": true},
+		wrappers: []patternWrapper{
+			{format: "class __PP { void __pp() {\n%s\n} }", body: "block"},
+			{format: "class __PP { void __pp() {\n%s;\n} }", body: "block"},
+			{format: "class __PP {\n%s\n}", body: "class_body"},
+		},
+		terminateEllipses: true,
+	},
+	"javascript": {language: sitter.NewLanguage(tsjavascript.Language()), strings: jsStrings, wrappers: jsWrapper},
+	"typescript": {language: sitter.NewLanguage(tstypescript.LanguageTypescript()), strings: jsStrings, wrappers: jsWrapper},
+	"tsx":        {language: sitter.NewLanguage(tstypescript.LanguageTSX()), strings: jsStrings, wrappers: jsWrapper},
+}
+
+// treeSitterSyntaxTree parses a file with the grammar of language. Tree-sitter recovers from
+// syntax errors, so files that do not fully parse are still matched where they do.
+func treeSitterSyntaxTree(language, content string) (*syntaxNode, error) {
+	root, _, err := parseTreeSitter(language, content)
+	return root, err
+}
+
+// treeSitterPatternNodes parses a prepared pattern with the first wrapper it parses in
+// without errors.
+func treeSitterPatternNodes(language, pattern string) ([]*syntaxNode, error) {
+	grammar, ok := treeSitterGrammars[language]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q", language)
+	}
+	if grammar.terminateEllipses {
+		pattern = ellipsisStatementRegex.ReplaceAllString(pattern, "$1;")
+	}
+	for _, wrapper := range grammar.wrappers {
+		root, hasError, err := parseTreeSitter(language, fmt.Sprintf(wrapper.format, pattern))
+		if err != nil {
+			return nil, err
+		}
+		if hasError {
+			continue
+		}
+		body := root
+		if wrapper.body != "" {
+			if body = findKind(root, wrapper.body); body == nil {
+				continue
+			}
+		}
+
+		var nodes []*syntaxNode
+		for _, child := range body.children {
+			if child.leaf && !child.bindable && (child.text == "{" || child.text == "}") {
+				continue
+			}
+			nodes = append(nodes, child)
+		}
+		if len(nodes) == 0 {
+			return nil, errors.New("pattern has no code")
+		}
+		return nodes, nil
+	}
+	return nil, fmt.Errorf("syntax error in %s pattern", language)
+}
+
+func parseTreeSitter(language, content string) (*syntaxNode, bool, error) {
+	grammar, ok := treeSitterGrammars[language]
+	if !ok {
+		return nil, false, fmt.Errorf("unsupported language %q", language)
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(grammar.language); err != nil {
+		return nil, false, err
+	}
+	src := []byte(content)
+	tree := parser.Parse(src, nil)
+	if tree == nil {
+		return nil, false, errors.New("tree-sitter returned no tree")
+	}
+	defer tree.Close()
+
+	root := tree.RootNode()
+	return treeSitterNode(root, src, grammar), root.HasError(), nil
+}
+
+func treeSitterNode(n *sitter.Node, src []byte, grammar *treeSitterGrammar) *syntaxNode {
+	node := &syntaxNode{
+		kind:     n.Kind(),
+		start:    int(n.StartByte()),
+		end:      int(n.EndByte()),
+		line:     int(n.StartPosition().Row) + 1,
+		bindable: n.IsNamed(),
+	}
+	text := string(src[node.start:node.end])
+	switch {
+	case grammar.strings[node.kind]:
+		node.leaf, node.str, node.text = true, true, normalizeString(text)
+	case n.ChildCount() == 0:
+		node.leaf, node.text = true, text
+	default:
+		for i := uint(0); i < n.ChildCount(); i++ {
+			child := n.Child(i)
+			if child == nil || child.IsExtra() {
+				continue // comments
+			}
+			node.children = append(node.children, treeSitterNode(child, src, grammar))
+		}
+	}
+	return node
+}
+
+// findKind returns the first node of a type, depth first.
+func findKind(n *syntaxNode, kind string) *syntaxNode {
+	if n.kind == kind {
+		return n
+	}
+	for _, child := range n.children {
+		if found := findKind(child, kind); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -8,15 +8,23 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/keploy/PullPilot/internal/config"
+	"github.com/keploy/PullPilot/internal/httpclient"
 	"github.com/keploy/PullPilot/pkg/models"
 )
 
 type Scanner struct {
 	cfg    *config.Config
 	client *http.Client
+
+	licenseOnce  sync.Once
+	licenseCache map[string]string
+
+	resolvedMu sync.Mutex
+	resolved   map[string]string
 }
 
 func NewScanner(cfg *config.Config) *Scanner {
@@ -26,7 +34,7 @@ func NewScanner(cfg *config.Config) *Scanner {
 
 	return &Scanner{
 		cfg:    cfg,
-		client: &http.Client{Timeout: 10 * time.Second},
+		client: httpclient.New("deps.dev", 10*time.Second),
 	}
 }
 
@@ -45,13 +53,13 @@ func (s *Scanner) Analyze(ctx context.Context, files []*models.File) ([]*models.
 			fmt.Println("Detected Go module file.")
 			deps := parseGoMod(file.Content)
 			fmt.Println("Parsed dependencies:", deps)
-			issues = append(issues, s.checkDeps(ctx, "go", deps)...)
+			issues = append(issues, s.checkDeps(ctx, file, "go", deps)...)
 
 		case "package.json":
 			fmt.Println("Detected package.json file.")
 			deps := parsePackageJSON(file.Content)
 			fmt.Println("Parsed dependencies:", deps)
-			issues = append(issues, s.checkDeps(ctx, "npm", deps)...)
+			issues = append(issues, s.checkDeps(ctx, file, "npm", deps)...)
 
 		default:
 			fmt.Println("Skipping file:", file.Path)
@@ -65,12 +73,23 @@ func (s *Scanner) Analyze(ctx context.Context, files []*models.File) ([]*models.
 	return issues, nil
 }
 
-func (s *Scanner) checkDeps(ctx context.Context, ecosystem string, deps map[string]string) []*models.Issue {
+// fetchVersionInfo returns the deps.dev record of one package version. A version deps.dev
+// does not know, or a rate-limited request, is an error rather than an empty record.
+func (s *Scanner) fetchVersionInfo(ctx context.Context, queryURL string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	if err := s.getJSON(ctx, queryURL, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Scanner) checkDeps(ctx context.Context, manifest *models.File, ecosystem string, deps map[string]string) []*models.Issue {
 	fmt.Println("********************************************************************************")
 	fmt.Printf("Checking dependencies for ecosystem: %s\n", ecosystem)
 	fmt.Println("********************************************************************************")
 
 	var issues []*models.Issue
+	added := addedDependencies(manifest, deps)
 
 	for pkg, version := range deps {
 		cleanVersion := strings.TrimLeft(version, "^~") // Remove ^ and ~
@@ -81,22 +100,28 @@ func (s *Scanner) checkDeps(ctx context.Context, ecosystem string, deps map[stri
 		fmt.Printf("Checking package: %s, original version: %s, cleaned version: %s\n", pkg, version, cleanVersion)
 		fmt.Println("Requesting URL:", queryURL)
 
-		resp, err := s.client.Get(queryURL)
-		if err != nil {
-			fmt.Println("Error fetching dependency info:", err)
-			continue
+		// The license check must not depend on deps.dev being reachable: the local cache
+		// still applies, and an unresolved license is reported rather than passed.
+		result, fetchErr := s.fetchVersionInfo(ctx, queryURL)
+		if fetchErr != nil {
+			fmt.Println("Error fetching dependency info:", fetchErr)
 		}
-		defer resp.Body.Close()
 
-		var result map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			fmt.Println("Error decoding JSON response:", err)
+		dep := models.Dependency{System: ecosystem, Name: pkg, Version: cleanVersion}
+		dep.License = s.resolveLicense(dep, result)
+		s.rememberLicense(dep)
+		if added[pkg] {
+			if issue := s.checkLicense(manifest, dep); issue != nil {
+				issues = append(issues, issue)
+			}
+			if s.cfg.EnableDependencyHealth && fetchErr == nil {
+				issues = append(issues, s.checkHealth(ctx, manifest, dep, result)...)
+			}
+		}
+		if fetchErr != nil {
 			continue
 		}
 
-		responseJSON, _ := json.MarshalIndent(result, "", "  ")
-		fmt.Println(string(responseJSON))
-
 		advisoryKeys, exists := result["advisoryKeys"].([]interface{})
 		if !exists || len(advisoryKeys) == 0 {
 			fmt.Println("No advisories found for", pkg)
@@ -148,6 +173,7 @@ func (s *Scanner) checkDeps(ctx context.Context, ecosystem string, deps map[stri
 					Description: fmt.Sprintf("%s (CVSS: %.1f)", title, cvssScore),
 					Severity:    models.SeverityError,
 					Source:      "deps.dev",
+					Category:    "vulnerability",
 				}
 				issues = append(issues, issue)
 			}
@@ -172,12 +198,23 @@ func parseGoMod(content string) map[string]string {
 	fmt.Println("********************************************************************************")
 
 	deps := make(map[string]string)
+	inRequireBlock := false
 	for _, line := range strings.Split(content, "\n") {
-		if strings.HasPrefix(line, "require ") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "require ("):
+			inRequireBlock = true
+		case inRequireBlock && line == ")":
+			inRequireBlock = false
+		case inRequireBlock:
+			parts := strings.Fields(line)
+			if len(parts) >= 2 && !strings.HasPrefix(parts[0], "//") {
+				deps[parts[0]] = parts[1]
+			}
+		case strings.HasPrefix(line, "require "):
 			parts := strings.Fields(line)
 			if len(parts) >= 3 {
 				deps[parts[1]] = parts[2]
-				fmt.Printf("Parsed dependency: %s -> %s\n", parts[1], parts[2])
 			}
 		}
 	}
@@ -0,0 +1,258 @@
+package dependency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const (
+	CategoryDeprecated   = "dependency-deprecated"
+	CategoryUnmaintained = "dependency-unmaintained"
+	CategoryLowScorecard = "dependency-low-scorecard"
+	CategoryTyposquat    = "dependency-typosquat"
+)
+
+// popularPackages are well-known package names that typosquats tend to imitate.
+var popularPackages = map[string][]string{
+	"npm": {
+		"react", "react-dom", "lodash", "express", "axios", "chalk", "commander", "debug",
+		"moment", "request", "typescript", "webpack", "babel-core", "eslint", "jest", "mocha",
+		"vue", "angular", "jquery", "underscore", "async", "bluebird", "uuid", "dotenv",
+		"cross-env", "colors", "minimist", "yargs", "body-parser", "mongoose", "socket.io",
+		"next", "prettier", "node-fetch", "rxjs", "redux", "classnames", "semver", "glob",
+	},
+	"go": {
+		"github.com/gin-gonic/gin", "github.com/gorilla/mux", "github.com/sirupsen/logrus",
+		"github.com/stretchr/testify", "github.com/spf13/cobra", "github.com/spf13/viper",
+		"github.com/google/uuid", "github.com/pkg/errors", "go.uber.org/zap",
+		"github.com/golang-jwt/jwt", "github.com/go-redis/redis", "github.com/lib/pq",
+		"github.com/jackc/pgx", "gorm.io/gorm", "github.com/labstack/echo",
+		"github.com/gofiber/fiber", "google.golang.org/grpc", "github.com/prometheus/client_golang",
+	},
+}
+
+// Edit distance says little about short names: color and colors, or uid and uuid, are one
+// edit apart and unrelated. Names shorter than minTyposquatLength are therefore only matched
+// after normalization, and one edit is allowed per typosquatCharsPerEdit characters of the
+// popular name, up to TyposquatMaxDistance.
+const (
+	minTyposquatLength    = 5
+	typosquatCharsPerEdit = 5
+)
+
+// knownLookalikes are established packages whose names are close to a popular one.
+var knownLookalikes = map[string][]string{
+	"npm": {"color", "preact", "tslint", "prettierx", "dotenvx", "mongodb", "requests"},
+}
+
+// checkHealth flags an added dependency that is deprecated, unmaintained, poorly scored by
+// OpenSSF Scorecard, or named like a popular package. versionInfo is the deps.dev version
+// metadata already fetched by checkDeps.
+func (s *Scanner) checkHealth(ctx context.Context, manifest *models.File, dep models.Dependency, versionInfo map[string]interface{}) []*models.Issue {
+	var issues []*models.Issue
+	line := dependencyLine(manifest.Content, dep.Name)
+
+	newIssue := func(category, title, description string) *models.Issue {
+		return &models.Issue{
+			Path:        manifest.Path,
+			Line:        line,
+			Title:       title,
+			Description: description,
+			Severity:    models.SeverityWarning,
+			Source:      "deps.dev",
+			Category:    category,
+		}
+	}
+
+	if deprecated, _ := versionInfo["isDeprecated"].(bool); deprecated {
+		reason, _ := versionInfo["deprecatedReason"].(string)
+		if reason == "" {
+			reason = "no reason given"
+		}
+		issues = append(issues, newIssue(CategoryDeprecated, "Deprecated Dependency",
+			fmt.Sprintf("%s@%s is deprecated (%s).", dep.Name, dep.Version, reason)))
+	}
+
+	if s.cfg.DependencyMaxAgeMonths > 0 {
+		if latest, err := s.latestRelease(ctx, dep); err != nil {
+			fmt.Println("Error fetching release history:", err)
+		} else if !latest.IsZero() && latest.Before(time.Now().AddDate(0, -s.cfg.DependencyMaxAgeMonths, 0)) {
+			issues = append(issues, newIssue(CategoryUnmaintained, "Unmaintained Dependency",
+				fmt.Sprintf("%s has had no release since %s (threshold: %d months).",
+					dep.Name, latest.Format("2006-01-02"), s.cfg.DependencyMaxAgeMonths)))
+		}
+	}
+
+	if s.cfg.ScorecardMinScore > 0 {
+		if project := sourceProject(versionInfo); project != "" {
+			if score, ok, err := s.scorecard(ctx, project); err != nil {
+				fmt.Println("Error fetching scorecard:", err)
+			} else if ok && score < s.cfg.ScorecardMinScore {
+				issues = append(issues, newIssue(CategoryLowScorecard, "Low OpenSSF Scorecard",
+					fmt.Sprintf("%s (%s) has an OpenSSF Scorecard score of %.1f, below the minimum of %.1f.",
+						dep.Name, project, score, s.cfg.ScorecardMinScore)))
+			}
+		}
+	}
+
+	if lookalike := s.typosquatTarget(dep); lookalike != "" {
+		issues = append(issues, newIssue(CategoryTyposquat, "Possible Typosquatted Dependency",
+			fmt.Sprintf("%s looks like the popular package %s. Make sure this is the package you intended.", dep.Name, lookalike)))
+	}
+
+	return issues
+}
+
+// latestRelease returns the publish time of the newest version of a package.
+func (s *Scanner) latestRelease(ctx context.Context, dep models.Dependency) (time.Time, error) {
+	var pkg struct {
+		Versions []struct {
+			PublishedAt time.Time `json:"publishedAt"`
+		} `json:"versions"`
+	}
+	queryURL := fmt.Sprintf("https://api.deps.dev/v3/systems/%s/packages/%s", dep.System, url.PathEscape(dep.Name))
+	if err := s.getJSON(ctx, queryURL, &pkg); err != nil {
+		return time.Time{}, err
+	}
+
+	var latest time.Time
+	for _, v := range pkg.Versions {
+		if v.PublishedAt.After(latest) {
+			latest = v.PublishedAt
+		}
+	}
+	return latest, nil
+}
+
+// scorecard returns the OpenSSF Scorecard overall score of a source project, if it has one.
+func (s *Scanner) scorecard(ctx context.Context, project string) (float64, bool, error) {
+	var result struct {
+		Scorecard *struct {
+			OverallScore float64 `json:"overallScore"`
+		} `json:"scorecard"`
+	}
+	queryURL := fmt.Sprintf("https://api.deps.dev/v3/projects/%s", url.PathEscape(project))
+	if err := s.getJSON(ctx, queryURL, &result); err != nil {
+		return 0, false, err
+	}
+	if result.Scorecard == nil {
+		return 0, false, nil
+	}
+	return result.Scorecard.OverallScore, true, nil
+}
+
+// sourceProject returns the source repository project key (e.g. "github.com/owner/repo")
+// from deps.dev version metadata.
+func sourceProject(versionInfo map[string]interface{}) string {
+	related, _ := versionInfo["relatedProjects"].([]interface{})
+	for _, r := range related {
+		project, ok := r.(map[string]interface{})
+		if !ok || project["relationType"] != "SOURCE_REPO" {
+			continue
+		}
+		if key, ok := project["projectKey"].(map[string]interface{}); ok {
+			if id, ok := key["id"].(string); ok {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// typosquatTarget returns the popular package a dependency name imitates, or "" if none.
+func (s *Scanner) typosquatTarget(dep models.Dependency) string {
+	if s.cfg.TyposquatMaxDistance <= 0 {
+		return ""
+	}
+
+	name := strings.ToLower(dep.Name)
+	normalized := normalizePackageName(name)
+	for _, known := range append(popularPackages[dep.System], knownLookalikes[dep.System]...) {
+		if name == known {
+			return ""
+		}
+	}
+	for _, popular := range popularPackages[dep.System] {
+		if sameScope(name, popular) {
+			continue
+		}
+		if normalized == normalizePackageName(popular) {
+			return popular
+		}
+		if len(name) < minTyposquatLength || len(popular) < minTyposquatLength {
+			continue
+		}
+		maxDistance := minInt(s.cfg.TyposquatMaxDistance, len(popular)/typosquatCharsPerEdit)
+		if levenshtein(name, popular) <= maxDistance {
+			return popular
+		}
+	}
+	return ""
+}
+
+// sameScope reports whether two packages share an npm scope or a Go module owner, such as
+// github.com/spf13/cobra and github.com/spf13/viper, which only one publisher can release to.
+func sameScope(a, b string) bool {
+	i, j := strings.LastIndex(a, "/"), strings.LastIndex(b, "/")
+	return i > 0 && i == j && a[:i] == b[:j]
+}
+
+func normalizePackageName(name string) string {
+	return strings.NewReplacer("-", "", "_", "", ".", "").Replace(name)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func (s *Scanner) getJSON(ctx context.Context, queryURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, queryURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", queryURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("deps.dev returned %s for %s", resp.Status, queryURL)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
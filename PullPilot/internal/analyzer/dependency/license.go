@@ -0,0 +1,278 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const (
+	CategoryLicenseForbidden = "license-forbidden"
+	CategoryLicenseUnknown   = "license-unknown"
+)
+
+// resolveLicense returns the license of a dependency, preferring the local license
+// cache over the deps.dev version metadata. It returns "" when neither knows it.
+func (s *Scanner) resolveLicense(dep models.Dependency, versionInfo map[string]interface{}) string {
+	s.licenseOnce.Do(s.loadLicenseCache)
+
+	for _, key := range []string{
+		dependencyKey(dep),
+		fmt.Sprintf("%s/%s", dep.System, dep.Name),
+		dep.Name,
+	} {
+		if license, ok := s.licenseCache[key]; ok && license != "" {
+			return license
+		}
+	}
+
+	licenses, ok := versionInfo["licenses"].([]interface{})
+	if !ok {
+		return ""
+	}
+	var names []string
+	for _, l := range licenses {
+		if name, ok := l.(string); ok && name != "" && !strings.EqualFold(name, "non-standard") {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, " AND ")
+}
+
+// rememberLicense records a resolved license so the SBOM can reuse it without another lookup.
+func (s *Scanner) rememberLicense(dep models.Dependency) {
+	s.resolvedMu.Lock()
+	defer s.resolvedMu.Unlock()
+	if s.resolved == nil {
+		s.resolved = make(map[string]string)
+	}
+	s.resolved[dependencyKey(dep)] = dep.License
+}
+
+// knownLicense returns the license resolved during the last scan, falling back to the cache.
+func (s *Scanner) knownLicense(dep models.Dependency) string {
+	s.resolvedMu.Lock()
+	license, ok := s.resolved[dependencyKey(dep)]
+	s.resolvedMu.Unlock()
+	if ok {
+		return license
+	}
+	return s.resolveLicense(dep, nil)
+}
+
+func dependencyKey(dep models.Dependency) string {
+	return fmt.Sprintf("%s/%s@%s", dep.System, dep.Name, dep.Version)
+}
+
+// loadLicenseCache reads the optional JSON license cache. Keys are "system/name@version",
+// "system/name" or a bare package name, values are SPDX license expressions.
+func (s *Scanner) loadLicenseCache() {
+	s.licenseCache = make(map[string]string)
+	if s.cfg.LicenseCachePath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(s.cfg.LicenseCachePath)
+	if err != nil {
+		fmt.Println("Error reading license cache:", err)
+		return
+	}
+	if err := json.Unmarshal(data, &s.licenseCache); err != nil {
+		fmt.Println("Error parsing license cache:", err)
+	}
+}
+
+// checkLicense raises an issue when an added dependency has a forbidden or unknown license.
+func (s *Scanner) checkLicense(manifest *models.File, dep models.Dependency) *models.Issue {
+	if dep.License == "" {
+		return &models.Issue{
+			Path:        manifest.Path,
+			Line:        dependencyLine(manifest.Content, dep.Name),
+			Title:       "Unknown Dependency License",
+			Description: fmt.Sprintf("Could not resolve the license of %s@%s. Add it to the license cache or verify it manually.", dep.Name, dep.Version),
+			Severity:    models.SeverityWarning,
+			Source:      "license-check",
+			Category:    CategoryLicenseUnknown,
+		}
+	}
+
+	if reason := s.licenseViolation(dep.License); reason != "" {
+		return &models.Issue{
+			Path:        manifest.Path,
+			Line:        dependencyLine(manifest.Content, dep.Name),
+			Title:       "Forbidden Dependency License",
+			Description: fmt.Sprintf("%s@%s is licensed under %s, which %s.", dep.Name, dep.Version, dep.License, reason),
+			Severity:    models.SeverityError,
+			Suggestion:  "Replace the dependency or get an exception from legal.",
+			Source:      "license-check",
+			Category:    CategoryLicenseForbidden,
+		}
+	}
+
+	return nil
+}
+
+// licenseViolation checks an SPDX license expression against the configured deny and allow
+// lists. Every operand of AND must pass and at least one of OR, since OR lets the user pick.
+// Deny entries match as case-insensitive prefixes so that "AGPL" covers "AGPL-3.0-only".
+func (s *Scanner) licenseViolation(expression string) string {
+	notDenied := func(license string) bool {
+		for _, denied := range s.cfg.LicenseDenyList {
+			if strings.HasPrefix(strings.ToLower(license), strings.ToLower(denied)) {
+				return false
+			}
+		}
+		return true
+	}
+	allowed := func(license string) bool {
+		if len(s.cfg.LicenseAllowList) == 0 {
+			return true
+		}
+		for _, allowed := range s.cfg.LicenseAllowList {
+			if strings.EqualFold(license, allowed) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// One choice of licenses must pass both lists.
+	if satisfiesLicense(expression, func(license string) bool { return notDenied(license) && allowed(license) }) {
+		return ""
+	}
+	if !satisfiesLicense(expression, notDenied) {
+		return "is on the license deny list"
+	}
+	return "is not on the license allow list"
+}
+
+// satisfiesLicense evaluates an SPDX expression with ok deciding each license. AND binds
+// tighter than OR, and "X WITH exception" is decided by X. An expression that does not parse
+// only passes when every license in it does.
+func satisfiesLicense(expression string, ok func(license string) bool) bool {
+	tokens := licenseTokens(expression)
+	p := &licenseParser{tokens: tokens, ok: ok}
+	result, valid := p.or()
+	if valid && p.pos == len(tokens) {
+		return result
+	}
+
+	for _, token := range tokens {
+		switch strings.ToUpper(token) {
+		case "AND", "OR", "WITH", "(", ")":
+			continue
+		}
+		if !ok(token) {
+			return false
+		}
+	}
+	return true
+}
+
+func licenseTokens(expression string) []string {
+	return strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression))
+}
+
+// licenseParser is a recursive descent parser over SPDX expression tokens.
+type licenseParser struct {
+	tokens []string
+	pos    int
+	ok     func(string) bool
+}
+
+func (p *licenseParser) peek(keyword string) bool {
+	return p.pos < len(p.tokens) && strings.EqualFold(p.tokens[p.pos], keyword)
+}
+
+func (p *licenseParser) or() (bool, bool) {
+	result, valid := p.and()
+	for valid && p.peek("OR") {
+		p.pos++
+		var next bool
+		next, valid = p.and()
+		result = result || next
+	}
+	return result, valid
+}
+
+func (p *licenseParser) and() (bool, bool) {
+	result, valid := p.term()
+	for valid && p.peek("AND") {
+		p.pos++
+		var next bool
+		next, valid = p.term()
+		result = result && next
+	}
+	return result, valid
+}
+
+func (p *licenseParser) term() (bool, bool) {
+	if p.pos >= len(p.tokens) {
+		return false, false
+	}
+	if p.peek("(") {
+		p.pos++
+		result, valid := p.or()
+		if !valid || !p.peek(")") {
+			return false, false
+		}
+		p.pos++
+		return result, true
+	}
+
+	license := p.tokens[p.pos]
+	switch strings.ToUpper(license) {
+	case "AND", "OR", "WITH", ")":
+		return false, false
+	}
+	p.pos++
+	if p.peek("WITH") {
+		if p.pos+1 >= len(p.tokens) {
+			return false, false
+		}
+		p.pos += 2
+	}
+	return p.ok(license), true
+}
+
+// addedDependencies returns the dependencies introduced or changed by the PR. Without a patch
+// every dependency is treated as added, since there is no way to tell them apart.
+func addedDependencies(manifest *models.File, deps map[string]string) map[string]bool {
+	added := make(map[string]bool)
+	if manifest.Status == "added" || manifest.Patch == "" {
+		for name := range deps {
+			added[name] = true
+		}
+		return added
+	}
+
+	for _, line := range diff.AddedLines(manifest.Patch) {
+		for _, token := range manifestTokens(line) {
+			if _, ok := deps[token]; ok {
+				added[token] = true
+			}
+		}
+	}
+	return added
+}
+
+func dependencyLine(content, name string) int {
+	for i, line := range strings.Split(content, "\n") {
+		for _, token := range manifestTokens(line) {
+			if token == name {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+func manifestTokens(line string) []string {
+	return strings.FieldsFunc(line, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '"' || r == ':' || r == ','
+	})
+}
@@ -0,0 +1,302 @@
+package dependency
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// SBOM is the software bill of materials built from the dependency manifests of a PR.
+type SBOM struct {
+	CycloneDX []byte
+	SPDX      []byte // Only set when SPDX output is enabled
+	Diff      *models.DependencyDiff
+}
+
+// GenerateSBOM builds a CycloneDX (and optionally SPDX) SBOM for the PR head and diffs it
+// against the base revision, reconstructed from the patches of the changed manifests among files.
+// With checkoutDir, a checkout of the PR head, every manifest of the repository is covered.
+// Without one only the changed manifests are, so the SBOM lists just the dependencies declared
+// by the manifests the PR touches.
+func (s *Scanner) GenerateSBOM(subject, checkoutDir string, files []*models.File) (*SBOM, error) {
+	var head, base []models.Dependency
+	changed := make(map[string]bool, len(files))
+	for _, file := range files {
+		changed[filepath.ToSlash(file.Path)] = true
+		headDeps, ok := manifestDependencies(file.Path, file.Content)
+		if !ok {
+			continue
+		}
+		head = append(head, headDeps...)
+
+		if file.Status != "added" {
+			baseDeps, _ := manifestDependencies(file.Path, diff.ReconstructBase(file.Content, file.Patch))
+			base = append(base, baseDeps...)
+		}
+	}
+	if checkoutDir != "" {
+		unchanged, err := checkoutDependencies(checkoutDir, changed)
+		if err != nil {
+			log.Printf("Warning: SBOM only covers the changed manifests: %v", err)
+		} else {
+			head = append(head, unchanged...)
+			base = append(base, unchanged...)
+		}
+	}
+	sortDependencies(head)
+	sortDependencies(base)
+
+	for i := range head {
+		head[i].License = s.knownLicense(head[i])
+	}
+
+	serial, err := newUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SBOM serial number: %w", err)
+	}
+	created := time.Now().UTC().Format(time.RFC3339)
+
+	sbom := &SBOM{Diff: diffDependencies(base, head)}
+	if sbom.CycloneDX, err = cycloneDXDocument(subject, serial, created, head); err != nil {
+		return nil, fmt.Errorf("failed to encode CycloneDX SBOM: %w", err)
+	}
+	if s.cfg.EnableSPDXSBOM {
+		if sbom.SPDX, err = spdxDocument(subject, serial, created, head); err != nil {
+			return nil, fmt.Errorf("failed to encode SPDX SBOM: %w", err)
+		}
+	}
+
+	log.Printf("Generated SBOM for %s with %d components", subject, len(head))
+	return sbom, nil
+}
+
+// checkoutDependencies returns the dependencies declared by the manifests of a checkout,
+// except those at the paths in skip. Vendored and installed packages are not descended into.
+func checkoutDependencies(root string, skip map[string]bool) ([]models.Dependency, error) {
+	var deps []models.Dependency
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			switch entry.Name() {
+			case ".git", "vendor", "node_modules", "testdata":
+				if path == root {
+					return nil
+				}
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch entry.Name() {
+		case "go.mod", "package.json":
+		default:
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil || skip[filepath.ToSlash(rel)] {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		manifestDeps, _ := manifestDependencies(rel, string(data))
+		deps = append(deps, manifestDeps...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the manifests of %s: %w", root, err)
+	}
+	return deps, nil
+}
+
+func manifestDependencies(path, content string) ([]models.Dependency, bool) {
+	var system string
+	var deps map[string]string
+
+	switch filepath.Base(path) {
+	case "go.mod":
+		system, deps = "go", parseGoMod(content)
+	case "package.json":
+		system, deps = "npm", parsePackageJSON(content)
+	default:
+		return nil, false
+	}
+
+	var result []models.Dependency
+	for name, version := range deps {
+		result = append(result, models.Dependency{
+			System:  system,
+			Name:    name,
+			Version: strings.TrimLeft(version, "^~"),
+		})
+	}
+	return result, true
+}
+
+func sortDependencies(deps []models.Dependency) {
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].System != deps[j].System {
+			return deps[i].System < deps[j].System
+		}
+		return deps[i].Name < deps[j].Name
+	})
+}
+
+func diffDependencies(base, head []models.Dependency) *models.DependencyDiff {
+	key := func(d models.Dependency) string { return d.System + "/" + d.Name }
+
+	baseByKey := make(map[string]models.Dependency)
+	for _, dep := range base {
+		baseByKey[key(dep)] = dep
+	}
+	headByKey := make(map[string]models.Dependency)
+	for _, dep := range head {
+		headByKey[key(dep)] = dep
+	}
+
+	result := &models.DependencyDiff{}
+	for _, dep := range head {
+		old, ok := baseByKey[key(dep)]
+		switch {
+		case !ok:
+			result.Added = append(result.Added, dep)
+		case old.Version != dep.Version:
+			result.Changed = append(result.Changed, models.DependencyChange{Dependency: dep, OldVersion: old.Version})
+		}
+	}
+	for _, dep := range base {
+		if _, ok := headByKey[key(dep)]; !ok {
+			result.Removed = append(result.Removed, dep)
+		}
+	}
+	return result
+}
+
+// purl returns the package URL of a dependency, see https://github.com/package-url/purl-spec.
+func purl(dep models.Dependency) string {
+	switch dep.System {
+	case "go":
+		return fmt.Sprintf("pkg:golang/%s@%s", dep.Name, url.PathEscape(dep.Version))
+	case "npm":
+		return fmt.Sprintf("pkg:npm/%s@%s", strings.Replace(dep.Name, "@", "%40", 1), url.PathEscape(dep.Version))
+	default:
+		return fmt.Sprintf("pkg:generic/%s@%s", dep.Name, url.PathEscape(dep.Version))
+	}
+}
+
+func cycloneDXDocument(subject, serial, created string, deps []models.Dependency) ([]byte, error) {
+	type license struct {
+		Expression string `json:"expression"`
+	}
+	type component struct {
+		Type     string    `json:"type"`
+		BOMRef   string    `json:"bom-ref"`
+		Name     string    `json:"name"`
+		Version  string    `json:"version"`
+		PURL     string    `json:"purl"`
+		Licenses []license `json:"licenses,omitempty"`
+	}
+
+	components := []component{}
+	for _, dep := range deps {
+		c := component{
+			Type:    "library",
+			BOMRef:  purl(dep),
+			Name:    dep.Name,
+			Version: dep.Version,
+			PURL:    purl(dep),
+		}
+		if dep.License != "" {
+			c.Licenses = []license{{Expression: dep.License}}
+		}
+		components = append(components, c)
+	}
+
+	doc := map[string]interface{}{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.5",
+		"serialNumber": "urn:uuid:" + serial,
+		"version":      1,
+		"metadata": map[string]interface{}{
+			"timestamp": created,
+			"tools": map[string]interface{}{
+				"components": []map[string]string{{"type": "application", "name": "PullPilot"}},
+			},
+			"component": map[string]string{"type": "application", "name": subject},
+		},
+		"components": components,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func spdxDocument(subject, serial, created string, deps []models.Dependency) ([]byte, error) {
+	type externalRef struct {
+		Category string `json:"referenceCategory"`
+		Type     string `json:"referenceType"`
+		Locator  string `json:"referenceLocator"`
+	}
+	type pkg struct {
+		Name             string        `json:"name"`
+		SPDXID           string        `json:"SPDXID"`
+		VersionInfo      string        `json:"versionInfo"`
+		DownloadLocation string        `json:"downloadLocation"`
+		LicenseConcluded string        `json:"licenseConcluded"`
+		LicenseDeclared  string        `json:"licenseDeclared"`
+		ExternalRefs     []externalRef `json:"externalRefs"`
+	}
+
+	packages := []pkg{}
+	for i, dep := range deps {
+		declared := dep.License
+		if declared == "" {
+			declared = "NOASSERTION"
+		}
+		packages = append(packages, pkg{
+			Name:             dep.Name,
+			SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", i+1),
+			VersionInfo:      dep.Version,
+			DownloadLocation: "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  declared,
+			ExternalRefs:     []externalRef{{Category: "PACKAGE-MANAGER", Type: "purl", Locator: purl(dep)}},
+		})
+	}
+
+	doc := map[string]interface{}{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              subject,
+		"documentNamespace": "https://pullpilot.dev/spdx/" + url.PathEscape(subject) + "-" + serial,
+		"creationInfo": map[string]interface{}{
+			"created":  created,
+			"creators": []string{"Tool: PullPilot"},
+		},
+		"packages": packages,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
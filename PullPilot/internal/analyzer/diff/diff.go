@@ -17,11 +17,11 @@ import (
 	// Import your llm and models packages if calling directly here
 	// "github.com/your-org/your-repo/pkg/llm"
 	// "github.com/your-org/your-repo/pkg/models"
+
+	"github.com/keploy/PullPilot/pkg/github"
 )
 
 const (
-	githubAPIURL    = "https://api.github.com"
-	requestTimeout  = 30 * time.Second
 	maxFilesPerPage = 100 // Max allowed by GitHub API for file listing
 )
 
@@ -59,51 +59,25 @@ type GitHubContent struct {
 
 // ownerName1, repoNaame, err := extractOwnerAndRepo(PullRequest_url)
 
-// getGitHubAPI performs a GET request to the GitHub API with appropriate headers.
-func getGitHubAPI(ctx context.Context, apiURL string, token string, acceptHeader string) (*http.Response, error) {
-	client := &http.Client{Timeout: requestTimeout}
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GitHub API request for %s: %w", apiURL, err)
-	}
-
-	// Set Headers
-	req.Header.Set("Authorization", "token "+token)
-	if acceptHeader != "" {
-		req.Header.Set("Accept", acceptHeader)
-	} else {
-		req.Header.Set("Accept", "application/vnd.github.v3+json") // Default to JSON
-	}
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28") // Recommended practice
-
-	log.Printf("GitHub API Request: GET %s (Accept: %s)", apiURL, req.Header.Get("Accept"))
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute GitHub API request for %s: %w", apiURL, err)
-	}
-
-	// Basic check for common client errors here (e.g., 4xx) before returning response
-	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body) // Read body for context even on error
-		resp.Body.Close()                     // Close immediately after reading
-		return nil, fmt.Errorf("GitHub API request failed for %s: Status %d %s, Body: %s", apiURL, resp.StatusCode, resp.Status, string(bodyBytes))
-	}
-
-	return resp, nil // Caller is responsible for closing resp.Body if status is OK
+// getGitHubAPI performs a GET request through the authenticated GitHub client, so that the
+// configured API URL, credentials and CA bundle apply.
+func getGitHubAPI(ctx context.Context, client *github.Client, path string, acceptHeader string) (*http.Response, error) {
+	log.Printf("GitHub API Request: GET %s%s (Accept: %s)", client.APIURL(), path, acceptHeader)
+	return client.Get(ctx, path, acceptHeader) // Caller is responsible for closing resp.Body
 }
 
 // GetDiffAndContentFromPR retrieves the diff and content of changed files for a specific GitHub Pull Request.
-func GetDiffAndContentFromPR(ctx context.Context, owner, repo string, pullNumber int, token string) (string, map[string][]byte, error) {
-	if owner == "" || repo == "" || pullNumber <= 0 || token == "" {
-		return "", nil, fmt.Errorf("owner, repo, pullNumber, and token must be provided")
+func GetDiffAndContentFromPR(ctx context.Context, client *github.Client, owner, repo string, pullNumber int) (string, map[string][]byte, error) {
+	if client == nil || owner == "" || repo == "" || pullNumber <= 0 {
+		return "", nil, fmt.Errorf("client, owner, repo and pullNumber must be provided")
 	}
 
 	log.Printf("Getting diff and file contents for %s/%s PR #%d", owner, repo, pullNumber)
 
 	// --- 1. Get the Pull Request Diff ---
 
-	diffURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", githubAPIURL, owner, repo, pullNumber)
-	diffResp, err := getGitHubAPI(ctx, diffURL, token, "application/vnd.github.v3.diff")
+	diffURL := fmt.Sprintf("/repos/%s/%s/pulls/%d", owner, repo, pullNumber)
+	diffResp, err := getGitHubAPI(ctx, client, diffURL, "application/vnd.github.v3.diff")
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to get PR diff: %w", err)
 	}
@@ -118,8 +92,8 @@ func GetDiffAndContentFromPR(ctx context.Context, owner, repo string, pullNumber
 
 	// --- 2. Get PR Metadata to find HEAD commit SHA ---
 	// Needed to fetch file contents at the correct version
-	prURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", githubAPIURL, owner, repo, pullNumber)
-	prInfoResp, err := getGitHubAPI(ctx, prURL, token, "application/vnd.github.v3+json")
+	prURL := fmt.Sprintf("/repos/%s/%s/pulls/%d", owner, repo, pullNumber)
+	prInfoResp, err := getGitHubAPI(ctx, client, prURL, "application/vnd.github.v3+json")
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to get PR metadata: %w", err)
 	}
@@ -139,10 +113,10 @@ func GetDiffAndContentFromPR(ctx context.Context, owner, repo string, pullNumber
 	var changedFiles []string // Store paths of non-deleted files
 	page := 1
 	for {
-		filesURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/files?per_page=%d&page=%d",
-			githubAPIURL, owner, repo, pullNumber, maxFilesPerPage, page)
+		filesURL := fmt.Sprintf("/repos/%s/%s/pulls/%d/files?per_page=%d&page=%d",
+			owner, repo, pullNumber, maxFilesPerPage, page)
 
-		filesResp, err := getGitHubAPI(ctx, filesURL, token, "application/vnd.github.v3+json")
+		filesResp, err := getGitHubAPI(ctx, client, filesURL, "application/vnd.github.v3+json")
 		if err != nil {
 			// If listing files fails, we might still return the diff, but content will be incomplete
 			log.Printf("Warning: Failed to list PR files (page %d): %v. File content map will be incomplete.", page, err)
@@ -186,13 +160,13 @@ func GetDiffAndContentFromPR(ctx context.Context, owner, repo string, pullNumber
 	for _, filePath := range changedFiles {
 		// URL encode the file path to handle spaces, special chars, etc.
 		encodedPath := url.PathEscape(filePath)
-		contentURL := fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s",
-			githubAPIURL, owner, repo, encodedPath, headSHA)
+		contentURL := fmt.Sprintf("/repos/%s/%s/contents/%s?ref=%s",
+			owner, repo, encodedPath, headSHA)
 
 		// Add a small delay if needed to avoid secondary rate limits, although usually not required for moderate PRs.
 		// time.Sleep(50 * time.Millisecond)
 
-		contentResp, err := getGitHubAPI(ctx, contentURL, token, "application/vnd.github.v3+json")
+		contentResp, err := getGitHubAPI(ctx, client, contentURL, "application/vnd.github.v3+json")
 		if err != nil {
 			log.Printf("Warning: Failed to get content for file '%s' at ref '%s': %v. Skipping content.", filePath, headSHA, err)
 			// Check for 404 specifically - might indicate submodule or non-file?
@@ -293,7 +267,7 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute) // Longer timeout for API calls
 	defer cancel()
 
-	diffContent, changedFilesContent, err := GetDiffAndContentFromPR(ctx, repoOwner, repoName, pullRequestNumber, githubToken)
+	diffContent, changedFilesContent, err := GetDiffAndContentFromPR(ctx, github.NewClient(githubToken), repoOwner, repoName, pullRequestNumber)
 	fmt.Printf("Diff content: %s\n", diffContent)
 	fmt.Printf("Changed files content: %v\n", changedFilesContent)
 	if err != nil {
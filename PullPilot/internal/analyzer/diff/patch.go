@@ -0,0 +1,127 @@
+package diff
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Hunk is a single "@@ -a,b +c,d @@" section of a unified diff patch.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Header   string
+	Lines    []Line
+}
+
+// Line is one line of a hunk. OldLine is 0 for added lines and NewLine is 0 for removed lines.
+type Line struct {
+	Kind    byte // '+', '-' or ' '
+	Content string
+	OldLine int
+	NewLine int
+}
+
+// ParsePatch parses the per-file patch returned by the GitHub files API into hunks.
+func ParsePatch(patch string) []*Hunk {
+	var hunks []*Hunk
+	var current *Hunk
+	oldLine, newLine := 0, 0
+
+	for _, raw := range strings.Split(patch, "\n") {
+		if m := hunkHeaderRegex.FindStringSubmatch(raw); m != nil {
+			current = &Hunk{
+				OldStart: atoiDefault(m[1], 0),
+				OldLines: atoiDefault(m[2], 1),
+				NewStart: atoiDefault(m[3], 0),
+				NewLines: atoiDefault(m[4], 1),
+				Header:   raw,
+			}
+			hunks = append(hunks, current)
+			oldLine, newLine = current.OldStart, current.NewStart
+			continue
+		}
+		if current == nil || raw == "" {
+			continue
+		}
+
+		switch raw[0] {
+		case '+':
+			current.Lines = append(current.Lines, Line{Kind: '+', Content: raw[1:], NewLine: newLine})
+			newLine++
+		case '-':
+			current.Lines = append(current.Lines, Line{Kind: '-', Content: raw[1:], OldLine: oldLine})
+			oldLine++
+		case ' ':
+			current.Lines = append(current.Lines, Line{Kind: ' ', Content: raw[1:], OldLine: oldLine, NewLine: newLine})
+			oldLine++
+			newLine++
+		}
+		// Lines such as "\ No newline at end of file" are ignored.
+	}
+
+	return hunks
+}
+
+// AddedLines returns the added lines of a patch keyed by their line number in the new file.
+func AddedLines(patch string) map[int]string {
+	added := make(map[int]string)
+	for _, hunk := range ParsePatch(patch) {
+		for _, line := range hunk.Lines {
+			if line.Kind == '+' {
+				added[line.NewLine] = line.Content
+			}
+		}
+	}
+	return added
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// ReconstructBase rebuilds the pre-change content of a file from its new content and patch
+// by reverting every hunk. Added files have no base and yield "".
+func ReconstructBase(content, patch string) string {
+	if patch == "" {
+		return content
+	}
+	hunks := ParsePatch(patch)
+	if len(hunks) == 1 && hunks[0].OldStart == 0 && hunks[0].OldLines == 0 {
+		return ""
+	}
+
+	newLines := strings.Split(content, "\n")
+	var base []string
+	next := 1 // next line of the new file that has not been copied yet
+	for _, hunk := range hunks {
+		for ; next < hunk.NewStart && next <= len(newLines); next++ {
+			base = append(base, newLines[next-1])
+		}
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case '-', ' ':
+				base = append(base, line.Content)
+			}
+			if line.Kind != '-' {
+				next++
+			}
+		}
+	}
+	for ; next <= len(newLines); next++ {
+		base = append(base, newLines[next-1])
+	}
+
+	return strings.Join(base, "\n")
+}
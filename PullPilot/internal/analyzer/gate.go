@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/internal/config"
+	"github.com/keploy/PullPilot/internal/policy"
+	"github.com/keploy/PullPilot/pkg/github"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const gateStatusContext = "pullpilot/quality-gate"
+
+// gateRules returns the merge-gate rules of GATE_POLICY_FILE, which the repository cannot
+// override, or else those of its trusted .pullpilot.yml (see trustedRepoConfig). serverPolicy
+// reports whether they were configured on the server.
+func (o *Orchestrator) gateRules(job *Job) (rules []config.GateRule, serverPolicy bool, err error) {
+	if o.cfg.GatePolicyPath != "" {
+		policyConfig, err := config.LoadRepoConfig(o.cfg.GatePolicyPath)
+		if err != nil {
+			return nil, true, err
+		}
+		return policyConfig.Gate.Rules, true, nil
+	}
+
+	serverPolicy = o.cfg.RepoConfigPath != ""
+	if job.repoConfigErr != nil {
+		return nil, serverPolicy, job.repoConfigErr
+	}
+	return job.repoConfig.Gate.Rules, serverPolicy, nil
+}
+
+// evaluateGate runs the merge-gate policy over the issues of a run. The outcome of a server
+// policy is the conclusion of the check run when the run has one; otherwise, and for policies
+// of the repository, it is reported as a commit status on the head of the pull request.
+// It returns nil when no policy is set.
+func (o *Orchestrator) evaluateGate(ctx context.Context, job *Job, pr *github.PullRequest, files []*models.File, issues []*models.Issue, hasCheckRun bool) *models.GateResult {
+	if !o.cfg.EnableQualityGate {
+		return nil
+	}
+
+	rules, serverPolicy, err := o.gateRules(job)
+	if err == nil && len(rules) == 0 {
+		return nil
+	}
+	var gate *policy.Gate
+	if err == nil {
+		gate, err = policy.Compile(rules)
+	}
+
+	input := &policy.Input{
+		Issues:       issues,
+		ChangedLines: make(map[string]map[int]string),
+		PR:           policy.PRInfo{Owner: job.RepoOwner, Repo: job.RepoName, Number: job.PRNumber},
+	}
+	for _, file := range files {
+		input.ChangedLines[file.Path] = diff.AddedLines(file.Patch)
+	}
+
+	if pr != nil {
+		input.PR = prInfo(job, pr)
+	}
+
+	var result *models.GateResult
+	if err != nil {
+		// A broken policy fails the gate rather than silently letting changes through.
+		result = &models.GateResult{
+			Reasons:     []string{fmt.Sprintf("Quality gate policy is invalid: %v", err)},
+			EvaluatedAt: time.Now(),
+		}
+	} else {
+		result = gate.Evaluate(input)
+	}
+	result.ServerPolicy = serverPolicy
+	log.Printf("Quality gate for %s/%s PR #%d: passed=%t %v", job.RepoOwner, job.RepoName, job.PRNumber, result.Passed, result.Reasons)
+
+	if (!hasCheckRun || !serverPolicy) && pr != nil {
+		state, description := "success", fmt.Sprintf("All %d gate rules passed", result.Rules)
+		if !result.Passed {
+			state, description = "failure", result.Reasons[0]
+			if len(result.Reasons) > 1 {
+				description = fmt.Sprintf("%s (and %d more)", description, len(result.Reasons)-1)
+			}
+		}
+		if err := job.client.CreateCommitStatus(ctx, job.RepoOwner, job.RepoName, pr.Head.SHA, state, gateStatusContext, description); err != nil {
+			log.Printf("Warning: Failed to report the quality gate: %v", err)
+		}
+	}
+	return result
+}
+
+func prInfo(job *Job, pr *github.PullRequest) policy.PRInfo {
+	info := policy.PRInfo{
+		Owner:        job.RepoOwner,
+		Repo:         job.RepoName,
+		Number:       job.PRNumber,
+		Title:        pr.Title,
+		Author:       pr.User.Login,
+		BaseRef:      pr.Base.Ref,
+		HeadRef:      pr.Head.Ref,
+		Draft:        pr.Draft,
+		ChangedFiles: pr.ChangedFiles,
+		Additions:    pr.Additions,
+		Deletions:    pr.Deletions,
+	}
+	for _, label := range pr.Labels {
+		info.Labels = append(info.Labels, label.Name)
+	}
+	return info
+}
@@ -0,0 +1,296 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/internal/httpclient"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const defaultWorkers = 4
+
+type AIConfig struct {
+	Model       string
+	MaxTokens   int
+	Temperature float64
+	MinSeverity models.Severity
+	// ContextLines is how many unchanged lines around each hunk are sent to the model.
+	ContextLines int
+
+	MaxFileSizeBytes int64
+	MaxPromptTokens  int // Per request; larger files are split into chunks
+	TokenBudget      int // Per PR; the smallest diffs are skipped first once it is spent
+
+	// StructuredOutput asks providers that support it to constrain replies to findingsSchema.
+	StructuredOutput bool
+	// SuggestFixes asks for an exact replacement of the flagged lines with each finding.
+	SuggestFixes bool
+
+	// GroundFindings checks each finding against the file and moves it up to GroundingWindow
+	// lines to the code it describes; GroundingMode decides what happens when that fails.
+	GroundFindings  bool
+	GroundingWindow int
+	GroundingMode   string
+
+	// Workers is how many files are reviewed in parallel; 0 uses the provider's concurrency limit.
+	Workers int
+
+	// Prices in USD per million tokens; 0 uses the list price of known models.
+	PromptPricePerMTok     float64
+	CompletionPricePerMTok float64
+
+	// ContextTokenBudget caps the related code from other files added to each prompt.
+	ContextTokenBudget int
+}
+
+// ContextProvider finds code outside a changed file that helps to review it, such as the
+// definitions of symbols it uses and the callers of functions it changes.
+type ContextProvider interface {
+	RelatedSnippets(file *models.File, hunks []*diff.Hunk) []models.CodeSnippet
+}
+
+// Analyzer reviews changed files with whichever LLMProvider it is given.
+type Analyzer struct {
+	provider        LLMProvider
+	config          *AIConfig
+	contextProvider ContextProvider
+	prompts         *prompts
+	cache           *ResponseCache
+}
+
+func NewAnalyzer(provider LLMProvider, cfg *AIConfig) *Analyzer {
+	return &Analyzer{
+		provider: provider,
+		config:   cfg,
+		prompts:  newPrompts(),
+	}
+}
+
+// ForRun returns a copy of the analyzer for one run, reviewing with the prompt of cfg and the
+// cross-file context of contextProvider, which may be nil. Runs share the LLM provider and the
+// response cache but nothing else, so concurrent runs do not see each other's prompts.
+func (a *Analyzer) ForRun(cfg *PromptConfig, contextProvider ContextProvider) (*Analyzer, error) {
+	p, err := compilePrompts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Prompt config applied: custom template %t, %d persona(s)", p.template != defaultTemplate, len(p.personas))
+
+	run := *a
+	run.prompts = p
+	run.contextProvider = contextProvider
+	return &run, nil
+}
+
+// SetCache enables reuse of findings for prompts that were already reviewed.
+func (a *Analyzer) SetCache(cache *ResponseCache) {
+	a.cache = cache
+}
+
+func (a *Analyzer) AnalyzeCode(ctx context.Context, files []*models.File) ([]*models.Issue, error) {
+	issues, _, err := a.AnalyzeCodeWithStats(ctx, files)
+	return issues, err
+}
+
+// AnalyzeCodeWithStats reviews files like AnalyzeCode and also reports the estimated token
+// usage and the files that were skipped because of their size or the token budget.
+// Files are reviewed by a bounded pool of workers; issues are returned in planning order
+// regardless of which file finishes first. When ctx expires, the files already reviewed are
+// returned and the rest are listed as skipped.
+func (a *Analyzer) AnalyzeCodeWithStats(ctx context.Context, files []*models.File) ([]*models.Issue, *models.AIStats, error) {
+	stats := &models.AIStats{}
+	planned := a.planFiles(files, stats)
+
+	type fileResult struct {
+		issues []*models.Issue
+		stats  models.AIStats
+		err    error
+		done   bool
+	}
+	results := make([]fileResult, len(planned))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := a.workers(len(planned))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// Each file gets its own stats so workers never share mutable state.
+				issues, err := a.analyzeFile(ctx, planned[i], &results[i].stats)
+				results[i].issues, results[i].err, results[i].done = issues, err, true
+			}
+		}()
+	}
+
+dispatch:
+	for i := range planned {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var allIssues []*models.Issue
+	for i, result := range results {
+		path := planned[i].file.Path
+		mergeStats(stats, &result.stats)
+
+		switch {
+		case !result.done, result.err != nil && ctx.Err() != nil:
+			stats.SkippedFiles = append(stats.SkippedFiles, models.SkippedFile{
+				Path:   path,
+				Reason: "analysis deadline reached before the file was reviewed",
+			})
+		case result.err != nil:
+			log.Printf("AI analysis failed for %s: %v", path, result.err)
+		default:
+			stats.FilesAnalyzed++
+			allIssues = append(allIssues, filterIssues(result.issues, a.config.MinSeverity)...)
+		}
+	}
+
+	if lookups := stats.CacheHits + stats.CacheMisses; lookups > 0 {
+		stats.CacheHitRatio = float64(stats.CacheHits) / float64(lookups)
+	}
+
+	fmt.Println("AnalyzeCode: Completed analysis with", len(allIssues), "total issues using", workers, "worker(s)")
+	return allIssues, stats, nil
+}
+
+// workers is the size of the review pool: AIConfig.Workers, capped by the provider's
+// concurrency limit so that idle workers do not just queue on the limiter.
+func (a *Analyzer) workers(files int) int {
+	workers := a.config.Workers
+	limit := httpclient.LimiterFor(a.provider.Name()).MaxConcurrent()
+	if workers <= 0 {
+		workers = limit
+	}
+	if limit > 0 && workers > limit {
+		workers = limit
+	}
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if workers > files {
+		workers = files
+	}
+	return workers
+}
+
+// mergeStats adds the per-file counters of src to dst.
+func mergeStats(dst, src *models.AIStats) {
+	dst.Responses += src.Responses
+	dst.ParseFailures += src.ParseFailures
+	dst.RepairAttempts += src.RepairAttempts
+	dst.RepairSuccesses += src.RepairSuccesses
+	dst.CacheHits += src.CacheHits
+	dst.CacheMisses += src.CacheMisses
+	dst.SuggestedFixes += src.SuggestedFixes
+	dst.RejectedFixes += src.RejectedFixes
+	dst.GroundingSnapped += src.GroundingSnapped
+	dst.GroundingMarked += src.GroundingMarked
+	dst.GroundingDropped += src.GroundingDropped
+	dst.Calls = append(dst.Calls, src.Calls...)
+	dst.Usage.Merge(src.Usage)
+}
+
+func shouldSkipFile(path string) bool {
+	ext := filepath.Ext(path)
+	skip := !(ext == ".go" || ext == ".js" || ext == ".ts" || ext == ".py")
+
+	return skip
+}
+
+func (a *Analyzer) analyzeFile(ctx context.Context, p *plannedFile, stats *models.AIStats) ([]*models.Issue, error) {
+	fmt.Println("Analyzing file:", p.file.Path, "in", len(p.prompts), "chunk(s)")
+
+	var issues []*models.Issue
+	for _, prompt := range p.prompts {
+		findings, err := a.reviewChunk(ctx, prompt, stats)
+		if err != nil {
+			return nil, err
+		}
+		chunkIssues := findingsToIssues(findings, p.file.Path)
+		if a.config.SuggestFixes {
+			attachFixes(chunkIssues, findings, p.file, p.hunks, stats)
+		}
+		if a.config.GroundFindings {
+			chunkIssues = a.groundIssues(chunkIssues, p.file, p.hunks, stats)
+		}
+		issues = append(issues, chunkIssues...)
+	}
+
+	return issuesInHunks(issues, p.hunks), nil
+}
+
+// reviewChunk returns the findings for one prompt, from the response cache when the same
+// provider, model, template and input were reviewed before.
+func (a *Analyzer) reviewChunk(ctx context.Context, prompt string, stats *models.AIStats) ([]aiFinding, error) {
+	key := cacheKey(a.provider.Name(), a.provider.Model(), a.prompts.source, prompt)
+	if a.cache != nil {
+		var cached []aiFinding
+		if a.cache.Get(key, &cached) {
+			stats.CacheHits++
+			return cached, nil
+		}
+		stats.CacheMisses++
+	}
+
+	response, err := a.generate(ctx, "review", prompt, a.findingsSchema(), stats)
+	if err != nil {
+		fmt.Println("Failed to analyze file:", err)
+		return nil, err
+	}
+
+	findings, err := a.parseFindings(ctx, response, stats)
+	if err != nil {
+		return nil, err
+	}
+	if findings == nil {
+		findings = []aiFinding{}
+	}
+	a.cache.Put(key, findings)
+	return findings, nil
+}
+
+// generate calls the provider, constraining the reply to schema with the provider's native
+// structured output when it has one. Transient HTTP failures are retried by the provider's
+// client, see the httpclient package.
+// The call's token usage, cost and latency are recorded in stats under purpose.
+func (a *Analyzer) generate(ctx context.Context, purpose, prompt string, schema map[string]interface{}, stats *models.AIStats) (string, error) {
+	ctx, usage := withUsage(ctx)
+	started := time.Now()
+
+	var response string
+	var err error
+	if structured, ok := a.provider.(StructuredProvider); ok && a.config.StructuredOutput {
+		response, err = structured.GenerateStructured(ctx, prompt, schema)
+	} else {
+		response, err = a.provider.GenerateContent(ctx, prompt)
+	}
+	if err == nil {
+		a.recordCall(stats, purpose, prompt, response, usage, started)
+	}
+	return response, err
+}
+
+func filterIssues(issues []*models.Issue, min models.Severity) []*models.Issue {
+	var filtered []*models.Issue
+	for _, issue := range issues {
+		if issue.Severity.AtLeast(min) {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,155 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCache is an on-disk cache of parsed LLM replies, one JSON file per key. Entries
+// expire after ttl, and the oldest entries are evicted once the cache grows past maxBytes.
+type ResponseCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+
+	mu sync.Mutex
+}
+
+type cacheEntry struct {
+	CreatedAt time.Time       `json:"created_at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+func NewResponseCache(dir string, ttl time.Duration, maxBytes int64) (*ResponseCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &ResponseCache{dir: dir, ttl: ttl, maxBytes: maxBytes}, nil
+}
+
+// cacheKey hashes the parts that determine a reply. Each part is length-prefixed so that
+// different splits of the same text do not collide.
+func cacheKey(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Get decodes the cached value for key into out. Expired or unreadable entries are misses.
+func (c *ResponseCache) Get(key string, out interface{}) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		os.Remove(c.path(key))
+		return false
+	}
+	if c.ttl > 0 && time.Since(entry.CreatedAt) > c.ttl {
+		os.Remove(c.path(key))
+		return false
+	}
+	return json.Unmarshal(entry.Value, out) == nil
+}
+
+// Put stores value under key and evicts entries to stay within the size limit.
+func (c *ResponseCache) Put(key string, value interface{}) {
+	if c == nil {
+		return
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Warning: Failed to encode cache entry: %v", err)
+		return
+	}
+	data, err := json.Marshal(cacheEntry{CreatedAt: time.Now(), Value: raw})
+	if err != nil {
+		log.Printf("Warning: Failed to encode cache entry: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tmp := c.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("Warning: Failed to write cache entry: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, c.path(key)); err != nil {
+		log.Printf("Warning: Failed to write cache entry: %v", err)
+		os.Remove(tmp)
+		return
+	}
+	c.prune()
+}
+
+// prune removes expired entries, then the least recently written ones until the cache fits
+// in maxBytes.
+func (c *ResponseCache) prune() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+
+	type cachedFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []cachedFile
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(c.dir, entry.Name())
+		if c.ttl > 0 && time.Since(info.ModTime()) > c.ttl {
+			os.Remove(path)
+			continue
+		}
+		files = append(files, cachedFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+
+	if c.maxBytes <= 0 || total <= c.maxBytes {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, file := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if os.Remove(file.path) == nil {
+			total -= file.size
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package llm
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// boundaryRegexes match lines that start a function, method or type, where a large hunk
+// can be split without cutting a declaration in half.
+var boundaryRegexes = map[string]*regexp.Regexp{
+	".go":   regexp.MustCompile(`^(func|type)\s`),
+	".py":   regexp.MustCompile(`^\s*(async\s+def|def|class)\s`),
+	".js":   regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?(function|class)\b|^\s*(export\s+)?(const|let)\s+\w+\s*=\s*(async\s*)?(\(|function)`),
+	".ts":   regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(abstract\s+)?(async\s+)?(function|class|interface)\b|^\s*(export\s+)?(const|let)\s+\w+\s*=\s*(async\s*)?(\(|function)`),
+	".java": regexp.MustCompile(`^\s*((public|private|protected|static|final|abstract|synchronized)\s+)+[\w<>\[\], ]+\s+\w+\s*\(|^\s*((public|private|protected|static|final|abstract)\s+)*(class|interface|enum|record)\s`),
+}
+
+// fileHunks returns the hunks to review. Files without a patch are reviewed as if every
+// line had been added.
+func fileHunks(file *models.File) []*diff.Hunk {
+	if hunks := diff.ParsePatch(file.Patch); len(hunks) > 0 {
+		return hunks
+	}
+
+	hunk := &diff.Hunk{NewStart: 1}
+	for i, line := range strings.Split(file.Content, "\n") {
+		hunk.Lines = append(hunk.Lines, diff.Line{Kind: '+', Content: line, NewLine: i + 1})
+	}
+	hunk.NewLines = len(hunk.Lines)
+	hunk.Header = fmt.Sprintf("@@ -0,0 +1,%d @@", hunk.NewLines)
+	return []*diff.Hunk{hunk}
+}
+
+// diffSize is the number of added and removed lines, used to rank files for the token budget.
+func diffSize(hunks []*diff.Hunk) int {
+	size := 0
+	for _, hunk := range hunks {
+		for _, line := range hunk.Lines {
+			if line.Kind != ' ' {
+				size++
+			}
+		}
+	}
+	return size
+}
+
+// chunkHunks groups hunks into prompts of at most maxTokens, splitting oversized hunks on
+// function/class boundaries where the language allows it. Hunks are rendered one after the
+// other in the prompt, so each is counted once and a chunk's size is kept as a running total
+// on top of the prompt without any hunks.
+func (a *Analyzer) chunkHunks(file *models.File, hunks []*diff.Hunk, related string) [][]*diff.Hunk {
+	maxTokens := a.config.MaxPromptTokens
+	if maxTokens <= 0 {
+		return [][]*diff.Hunk{hunks}
+	}
+
+	overhead := a.provider.EstimateTokens(a.buildPrompt(file, nil, related))
+	fileLines := strings.Split(file.Content, "\n")
+	hunkTokens := func(hunk *diff.Hunk) int {
+		var builder strings.Builder
+		writeHunk(&builder, fileLines, hunk, a.config.ContextLines)
+		// One more, as estimates of the parts may each round down where the whole would not.
+		return a.provider.EstimateTokens(builder.String()) + 1
+	}
+
+	type piece struct {
+		hunk   *diff.Hunk
+		tokens int
+	}
+	var pieces []piece
+	for _, hunk := range hunks {
+		if tokens := hunkTokens(hunk); overhead+tokens <= maxTokens {
+			pieces = append(pieces, piece{hunk, tokens})
+			continue
+		}
+		for _, sub := range a.splitHunk(file, hunk, maxTokens-overhead) {
+			pieces = append(pieces, piece{sub, hunkTokens(sub)})
+		}
+	}
+
+	var chunks [][]*diff.Hunk
+	var current []*diff.Hunk
+	total := overhead
+	for _, p := range pieces {
+		if len(current) > 0 && total+p.tokens > maxTokens {
+			chunks = append(chunks, current)
+			current, total = nil, overhead
+		}
+		current = append(current, p.hunk)
+		total += p.tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// splitHunk cuts a hunk into consecutive sub-hunks that each fit in budget, the tokens left
+// for the diff, preferring to cut just before the most recent function/class boundary.
+func (a *Analyzer) splitHunk(file *models.File, hunk *diff.Hunk, budget int) []*diff.Hunk {
+	boundary := boundaryRegexes[filepath.Ext(file.Path)]
+	// Leave room for the surrounding context lines.
+	limit := budget * 9 / 10
+
+	var pieces []*diff.Hunk
+	start, lastBoundary, tokens := 0, -1, 0
+	for i, line := range hunk.Lines {
+		if boundary != nil && i > start && boundary.MatchString(line.Content) {
+			lastBoundary = i
+		}
+		tokens += a.provider.EstimateTokens(line.Content) + 2
+		if tokens <= limit || i == start {
+			continue
+		}
+
+		cut := i
+		if lastBoundary > start {
+			cut = lastBoundary
+		}
+		pieces = append(pieces, subHunk(hunk.Lines[start:cut]))
+		start, lastBoundary, tokens = cut, -1, 0
+		for _, l := range hunk.Lines[start : i+1] {
+			tokens += a.provider.EstimateTokens(l.Content) + 2
+		}
+	}
+	if start < len(hunk.Lines) {
+		pieces = append(pieces, subHunk(hunk.Lines[start:]))
+	}
+	return pieces
+}
+
+func subHunk(lines []diff.Line) *diff.Hunk {
+	hunk := &diff.Hunk{Lines: lines}
+	for _, line := range lines {
+		if line.NewLine != 0 {
+			if hunk.NewStart == 0 {
+				hunk.NewStart = line.NewLine
+			}
+			hunk.NewLines++
+		}
+		if line.OldLine != 0 {
+			if hunk.OldStart == 0 {
+				hunk.OldStart = line.OldLine
+			}
+			hunk.OldLines++
+		}
+	}
+	hunk.Header = fmt.Sprintf("@@ -%d,%d +%d,%d @@", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+	return hunk
+}
+
+type plannedFile struct {
+	file    *models.File
+	hunks   []*diff.Hunk
+	prompts []string
+	tokens  int
+}
+
+// planFiles builds the prompts for every reviewable file and applies the size limit and the
+// per-PR token budget. Files are ranked by diff size so the largest changes are reviewed first
+// when the budget runs out.
+func (a *Analyzer) planFiles(files []*models.File, stats *models.AIStats) []*plannedFile {
+	var planned []*plannedFile
+	for _, file := range files {
+		if shouldSkipFile(file.Path) {
+			fmt.Println("Skipping file:", file.Path)
+			continue
+		}
+		if a.config.MaxFileSizeBytes > 0 && int64(len(file.Content)) > a.config.MaxFileSizeBytes {
+			stats.SkippedFiles = append(stats.SkippedFiles, models.SkippedFile{
+				Path:   file.Path,
+				Reason: fmt.Sprintf("file is larger than %d bytes", a.config.MaxFileSizeBytes),
+			})
+			continue
+		}
+
+		p := &plannedFile{file: file, hunks: fileHunks(file)}
+		related := a.relatedCode(file, p.hunks, stats)
+		for _, chunk := range a.chunkHunks(file, p.hunks, related) {
+			prompt := a.buildPrompt(file, chunk, related)
+			p.prompts = append(p.prompts, prompt)
+			p.tokens += a.provider.EstimateTokens(prompt)
+		}
+		planned = append(planned, p)
+	}
+
+	sort.SliceStable(planned, func(i, j int) bool {
+		return diffSize(planned[i].hunks) > diffSize(planned[j].hunks)
+	})
+
+	var kept []*plannedFile
+	used := 0
+	for _, p := range planned {
+		if a.config.TokenBudget > 0 && used+p.tokens > a.config.TokenBudget {
+			stats.SkippedFiles = append(stats.SkippedFiles, models.SkippedFile{
+				Path:   p.file.Path,
+				Reason: fmt.Sprintf("per-PR token budget of %d exhausted (needs ~%d tokens)", a.config.TokenBudget, p.tokens),
+			})
+			continue
+		}
+		used += p.tokens
+		kept = append(kept, p)
+	}
+	stats.EstimatedPromptTokens = used
+	return kept
+}
+
+// relatedCode renders the cross-file context for a file within the context token budget.
+func (a *Analyzer) relatedCode(file *models.File, hunks []*diff.Hunk, stats *models.AIStats) string {
+	if a.contextProvider == nil {
+		return ""
+	}
+	related, included := formatRelatedCode(a.contextProvider.RelatedSnippets(file, hunks), a.config.ContextTokenBudget, a.provider.EstimateTokens)
+	stats.ContextSnippets += included
+	return related
+}
+
+// estimateTokens approximates a token count from the text length for a given tokenizer density.
+func estimateTokens(text string, charsPerToken float64) int {
+	return int(float64(len(text))/charsPerToken) + 1
+}
@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// responseFormatWithFixes extends responseFormat with an optional exact replacement.
+const responseFormatWithFixes = `Respond with a single JSON object and nothing else:
+{"findings": [{
+	"line": <number>,
+	"category": "security|performance|maintainability|error_handling",
+	"description": "<concise issue description>",
+	"severity": "high|medium|low",
+	"suggestion": "<specific improvement suggestion>",
+	"confidence": 0-1,
+	"fix": {
+		"start_line": <first new-file line to replace, 0 when there is no concrete fix>,
+		"end_line": <last new-file line to replace>,
+		"original": "<those lines copied verbatim, without line numbers or diff markers>",
+		"replacement": "<the code that replaces them, with the same indentation>"
+	}
+}]}
+Only propose a fix for lines marked "+" within a single hunk, and only when it is a complete,
+drop-in replacement. Use {"findings": []} when there is nothing to report.`
+
+type aiFix struct {
+	StartLine   int    `json:"start_line"`
+	EndLine     int    `json:"end_line"`
+	Original    string `json:"original"`
+	Replacement string `json:"replacement"`
+}
+
+// findingsSchemaWithFixes is findingsSchema with the fix object added to every finding.
+var findingsSchemaWithFixes = func() map[string]interface{} {
+	item := map[string]interface{}{}
+	base := findingsSchema["properties"].(map[string]interface{})["findings"].(map[string]interface{})["items"].(map[string]interface{})
+	for k, v := range base {
+		item[k] = v
+	}
+
+	properties := map[string]interface{}{}
+	for k, v := range base["properties"].(map[string]interface{}) {
+		properties[k] = v
+	}
+	properties["fix"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"start_line":  map[string]interface{}{"type": "integer"},
+			"end_line":    map[string]interface{}{"type": "integer"},
+			"original":    map[string]interface{}{"type": "string"},
+			"replacement": map[string]interface{}{"type": "string"},
+		},
+		"required":             []string{"start_line", "end_line", "original", "replacement"},
+		"additionalProperties": false,
+	}
+	item["properties"] = properties
+	item["required"] = append(append([]string{}, base["required"].([]string)...), "fix")
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"findings": map[string]interface{}{"type": "array", "items": item},
+		},
+		"required":             []string{"findings"},
+		"additionalProperties": false,
+	}
+}()
+
+func (a *Analyzer) findingsSchema() map[string]interface{} {
+	if a.config.SuggestFixes {
+		return findingsSchemaWithFixes
+	}
+	return findingsSchema
+}
+
+func (a *Analyzer) responseFormat() string {
+	if a.config.SuggestFixes {
+		return responseFormatWithFixes
+	}
+	return responseFormat
+}
+
+// attachFixes turns the fixes of findings into issue fixes, keeping only those that apply
+// cleanly: the range lies within one hunk of the diff, "original" matches those exact lines
+// of the file, and the replacement actually changes them. Rejected fixes leave the prose
+// suggestion in place.
+func attachFixes(issues []*models.Issue, findings []aiFinding, file *models.File, hunks []*diff.Hunk, stats *models.AIStats) {
+	// findingsToIssues drops low-confidence findings, so match issues back by position.
+	next := 0
+	for _, f := range findings {
+		if *f.Confidence < minConfidence {
+			continue
+		}
+		issue := issues[next]
+		next++
+
+		if f.Fix == nil || f.Fix.StartLine == 0 {
+			continue
+		}
+		if err := validateFix(f.Fix, file, hunks); err != nil {
+			log.Printf("Dropping suggested fix for %s:%d: %v", file.Path, issue.Line, err)
+			stats.RejectedFixes++
+			continue
+		}
+
+		issue.Fix = &models.CodeFix{
+			StartLine:   f.Fix.StartLine,
+			EndLine:     f.Fix.EndLine,
+			Replacement: strings.TrimRight(f.Fix.Replacement, "\n"),
+		}
+		stats.SuggestedFixes++
+	}
+}
+
+func validateFix(fix *aiFix, file *models.File, hunks []*diff.Hunk) error {
+	lines := strings.Split(file.Content, "\n")
+	if fix.StartLine < 1 || fix.EndLine < fix.StartLine || fix.EndLine > len(lines) {
+		return fmt.Errorf("line range %d-%d is outside the file", fix.StartLine, fix.EndLine)
+	}
+	if !rangeInOneHunk(fix.StartLine, fix.EndLine, hunks) {
+		return fmt.Errorf("line range %d-%d is not within a single hunk", fix.StartLine, fix.EndLine)
+	}
+
+	current := strings.Join(lines[fix.StartLine-1:fix.EndLine], "\n")
+	if normalizeNewlines(current) != normalizeNewlines(strings.TrimRight(fix.Original, "\n")) {
+		return fmt.Errorf("original text does not match lines %d-%d", fix.StartLine, fix.EndLine)
+	}
+	if normalizeNewlines(current) == normalizeNewlines(strings.TrimRight(fix.Replacement, "\n")) {
+		return fmt.Errorf("replacement does not change lines %d-%d", fix.StartLine, fix.EndLine)
+	}
+	return nil
+}
+
+func rangeInOneHunk(start, end int, hunks []*diff.Hunk) bool {
+	for _, hunk := range hunks {
+		if start >= hunk.NewStart && end < hunk.NewStart+hunk.NewLines {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeNewlines(text string) string {
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const (
+	GroundingDrop = "drop" // Ungrounded findings are removed
+	GroundingMark = "mark" // Ungrounded findings are kept as low-confidence info
+)
+
+var (
+	backtickRegex = regexp.MustCompile("`([^`\n]+)`")
+	// codeTokenRegex matches words that look like code rather than prose: calls, selectors,
+	// snake_case and camelCase/PascalCase identifiers with an inner capital.
+	codeTokenRegex = regexp.MustCompile(`[A-Za-z_][\w]*(?:\.[A-Za-z_]\w*)+(?:\(\))?|[A-Za-z_]\w*\(\)|[A-Za-z]*_[\w]+|[a-z]+[A-Z]\w*|[A-Z][a-z0-9]+[A-Z]\w*`)
+	identRegex     = regexp.MustCompile(`[A-Za-z_]\w*`)
+)
+
+// groundIssues checks AI findings against the file. A finding whose line does not exist, or
+// whose description names identifiers that do not appear on it, is moved to the line within
+// the grounding window that mentions most of them. Findings that cannot be grounded are
+// dropped or, in GroundingMark mode, kept as low-confidence info.
+func (a *Analyzer) groundIssues(issues []*models.Issue, file *models.File, hunks []*diff.Hunk, stats *models.AIStats) []*models.Issue {
+	lines := strings.Split(file.Content, "\n")
+	window := a.config.GroundingWindow
+
+	var kept []*models.Issue
+	for _, issue := range issues {
+		// Fixes were already matched against the exact lines they replace.
+		if issue.Fix != nil {
+			kept = append(kept, issue)
+			continue
+		}
+
+		line, ok := groundLine(issue, lines, hunks, window)
+		switch {
+		case ok && line != issue.Line:
+			log.Printf("Moved AI finding %s:%d to line %d", issue.Path, issue.Line, line)
+			issue.Line = line
+			stats.GroundingSnapped++
+		case ok:
+		case a.config.GroundingMode == GroundingMark:
+			log.Printf("Marking ungrounded AI finding %s:%d as low confidence", issue.Path, issue.Line)
+			issue.Severity = models.SeverityInfo
+			issue.Description += "\n\n_Low confidence: this finding could not be matched to the code it describes._"
+			stats.GroundingMarked++
+		default:
+			log.Printf("Dropping ungrounded AI finding %s:%d: %s", issue.Path, issue.Line, issue.Description)
+			stats.GroundingDropped++
+			continue
+		}
+		kept = append(kept, issue)
+	}
+	return kept
+}
+
+// groundLine returns the line a finding should be anchored to, or false if none fits.
+func groundLine(issue *models.Issue, lines []string, hunks []*diff.Hunk, window int) (int, bool) {
+	identifiers := mentionedIdentifiers(issue.Description + "\n" + issue.Suggestion)
+
+	best, bestScore := 0, 0
+	for distance := 0; distance <= window; distance++ {
+		for _, candidate := range []int{issue.Line - distance, issue.Line + distance} {
+			if candidate < 1 || candidate > len(lines) || strings.TrimSpace(lines[candidate-1]) == "" {
+				continue
+			}
+			if len(hunks) > 0 && !lineInHunks(candidate, hunks) {
+				continue
+			}
+			if len(identifiers) == 0 {
+				// Nothing to match on: only require a real line, as close as possible.
+				return candidate, true
+			}
+			// Strictly greater keeps the closest line on ties.
+			if score := matchScore(lines[candidate-1], identifiers); score > bestScore {
+				best, bestScore = candidate, score
+			}
+			if distance == 0 {
+				break
+			}
+		}
+	}
+	return best, bestScore > 0
+}
+
+// mentionedIdentifiers extracts the code identifiers a finding talks about: anything in
+// backticks plus words that look like code. Selectors and calls are split into their parts.
+func mentionedIdentifiers(text string) []string {
+	var spans []string
+	for _, m := range backtickRegex.FindAllStringSubmatch(text, -1) {
+		spans = append(spans, m[1])
+	}
+	spans = append(spans, codeTokenRegex.FindAllString(backtickRegex.ReplaceAllString(text, " "), -1)...)
+
+	seen := make(map[string]bool)
+	var identifiers []string
+	for _, span := range spans {
+		for _, ident := range identRegex.FindAllString(span, -1) {
+			if len(ident) < 3 || seen[ident] || commonWords[strings.ToLower(ident)] {
+				continue
+			}
+			seen[ident] = true
+			identifiers = append(identifiers, ident)
+		}
+	}
+	return identifiers
+}
+
+// commonWords are keywords and prose that often appear in backticks but say nothing about
+// where a finding is.
+var commonWords = map[string]bool{
+	"nil": true, "null": true, "none": true, "true": true, "false": true, "err": true,
+	"error": true, "string": true, "int": true, "bool": true, "func": true, "function": true,
+	"return": true, "var": true, "const": true, "let": true, "def": true, "self": true, "this": true,
+	"the": true, "and": true, "for": true, "not": true,
+}
+
+// matchScore counts the identifiers that occur as whole words on a line.
+func matchScore(line string, identifiers []string) int {
+	words := make(map[string]bool)
+	for _, word := range identRegex.FindAllString(line, -1) {
+		words[word] = true
+	}
+	score := 0
+	for _, ident := range identifiers {
+		if words[ident] {
+			score++
+		}
+	}
+	return score
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/keploy/PullPilot/internal/httpclient"
+)
+
+const defaultOllamaModel = "llama3.1"
+
+// OllamaClient is the LLMProvider for a self-hosted Ollama server's /api/chat endpoint.
+type OllamaClient struct {
+	baseURL    string
+	httpClient *http.Client
+	config     *AIConfig
+}
+
+func NewOllamaClient(baseURL string, cfg *AIConfig) *OllamaClient {
+	return &OllamaClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		// Local models are slow on CPU-only hosts.
+		httpClient: httpclient.NewIdempotent("ollama", 5*time.Minute),
+		config:     cfg,
+	}
+}
+
+func (o *OllamaClient) Name() string {
+	return "ollama"
+}
+
+func (o *OllamaClient) Model() string {
+	return modelOrDefault(o.config, defaultOllamaModel)
+}
+
+// EstimateTokens assumes the denser Llama-family tokenizers, at about 3.5 characters per token.
+func (o *OllamaClient) EstimateTokens(text string) int {
+	return estimateTokens(text, 3.5)
+}
+
+func (o *OllamaClient) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	return o.generate(ctx, prompt, nil)
+}
+
+// GenerateStructured constrains the reply to schema using the provider's native structured output.
+func (o *OllamaClient) GenerateStructured(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	return o.generate(ctx, prompt, schema)
+}
+
+func (o *OllamaClient) generate(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	fmt.Println("Generating content with Ollama model", o.Model(), "for prompt of length:", len(prompt))
+
+	requestBody := map[string]interface{}{
+		"model": o.Model(),
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"stream": false,
+		"options": map[string]interface{}{
+			"temperature": o.config.Temperature,
+			"num_predict": o.config.MaxTokens,
+		},
+	}
+
+	if schema != nil {
+		requestBody["format"] = schema
+	}
+
+	jsonBody, _ := json.Marshal(requestBody)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/chat", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body := readBody(resp)
+		fmt.Println("API Error:", resp.StatusCode, body)
+		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, body)
+	}
+
+	var response struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		PromptEvalCount int `json:"prompt_eval_count"`
+		EvalCount       int `json:"eval_count"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	reportUsage(ctx, response.PromptEvalCount, response.EvalCount)
+
+	if response.Message.Content == "" {
+		return "", fmt.Errorf("no content in response")
+	}
+
+	return response.Message.Content, nil
+}
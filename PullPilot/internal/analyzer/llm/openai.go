@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/keploy/PullPilot/internal/httpclient"
+)
+
+const defaultOpenAIModel = "gpt-4o-mini"
+
+// OpenAIClient is the LLMProvider for any OpenAI-compatible chat completions endpoint
+// (OpenAI, Azure OpenAI proxies, vLLM, LiteLLM, LM Studio, ...).
+type OpenAIClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+	config     *AIConfig
+
+	// Set once the endpoint rejected response_format; later calls go without it.
+	structuredUnsupported atomic.Bool
+}
+
+// apiError is a non-200 reply from a chat completions endpoint.
+type apiError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+func NewOpenAIClient(baseURL, apiKey string, cfg *AIConfig) *OpenAIClient {
+	return &OpenAIClient{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpclient.NewIdempotent("openai", 60*time.Second),
+		config:     cfg,
+	}
+}
+
+func (o *OpenAIClient) Name() string {
+	return "openai"
+}
+
+func (o *OpenAIClient) Model() string {
+	return modelOrDefault(o.config, defaultOpenAIModel)
+}
+
+// EstimateTokens approximates cl100k/o200k tokenization at about four characters per token.
+func (o *OpenAIClient) EstimateTokens(text string) int {
+	return estimateTokens(text, 4)
+}
+
+func (o *OpenAIClient) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	return o.generate(ctx, prompt, nil)
+}
+
+// GenerateStructured constrains the reply to schema using the provider's native structured output.
+// Many OpenAI-compatible servers do not support json_schema response formats; when the endpoint
+// rejects the request it is retried as plain text, leaving the analyzer's validation and repair
+// round-trip to enforce the schema.
+func (o *OpenAIClient) GenerateStructured(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	if o.structuredUnsupported.Load() {
+		return o.generate(ctx, prompt, nil)
+	}
+
+	response, err := o.generate(ctx, prompt, schema)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity) {
+		log.Printf("Warning: %s rejected structured output (%d), falling back to plain generation", o.baseURL, apiErr.StatusCode)
+		o.structuredUnsupported.Store(true)
+		return o.generate(ctx, prompt, nil)
+	}
+	return response, err
+}
+
+func (o *OpenAIClient) generate(ctx context.Context, prompt string, schema map[string]interface{}) (string, error) {
+	fmt.Println("Generating content with", o.Model(), "for prompt of length:", len(prompt))
+
+	requestBody := map[string]interface{}{
+		"model": o.Model(),
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"temperature": o.config.Temperature,
+		"max_tokens":  o.config.MaxTokens,
+	}
+
+	if schema != nil {
+		requestBody["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   "findings",
+				"strict": true,
+				"schema": schema,
+			},
+		}
+	}
+
+	jsonBody, _ := json.Marshal(requestBody)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body := readBody(resp)
+		fmt.Println("API Error:", resp.StatusCode, body)
+		return "", &apiError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	reportUsage(ctx, response.Usage.PromptTokens, response.Usage.CompletionTokens)
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("no content in response")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/keploy/PullPilot/internal/config"
+)
+
+var defaultTemplate = template.Must(template.New("review").Parse(defaultPromptTemplate))
+
+// builtinPersonas are the review personas that can be enabled by name alone.
+var builtinPersonas = map[string]string{
+	"security": "Act as a security reviewer. Look for injection, missing authentication or authorization checks, " +
+		"unsafe deserialization, secrets in code, weak cryptography, path traversal and SSRF.",
+	"performance": "Act as a performance reviewer. Look for work inside hot loops, N+1 queries, unbounded allocations, " +
+		"missing pagination, needless copies and blocking calls on latency-sensitive paths.",
+	"api-compat": "Act as an API compatibility reviewer. Flag changes to exported functions, types, HTTP endpoints, " +
+		"wire formats or configuration that break existing callers without a migration path.",
+	"docs": "Act as a documentation reviewer. Flag exported or public symbols without documentation, comments " +
+		"that no longer match the code and user-visible behaviour changes that are not documented.",
+}
+
+// Persona adds review instructions for the files matching Paths, or every file when Paths is empty.
+type Persona struct {
+	Name         string
+	Paths        []string
+	Instructions string
+}
+
+// PromptConfig customizes the review prompt. The zero value uses the built-in template.
+type PromptConfig struct {
+	Template   string
+	Guidelines string
+	Personas   []Persona
+}
+
+type prompts struct {
+	source     string // Template text, part of the response cache key
+	template   *template.Template
+	guidelines string
+	personas   []Persona
+}
+
+func newPrompts() *prompts {
+	return &prompts{source: defaultPromptTemplate, template: defaultTemplate}
+}
+
+// compilePrompts parses the review prompt template and resolves the personas of cfg. An invalid
+// template or an unknown persona without instructions is an error.
+func compilePrompts(cfg *PromptConfig) (*prompts, error) {
+	p := newPrompts()
+	p.guidelines = strings.TrimSpace(cfg.Guidelines)
+
+	if strings.TrimSpace(cfg.Template) != "" {
+		tmpl, err := template.New("review").Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt template: %w", err)
+		}
+		// Catch references to unknown variables now rather than on every file.
+		if err := tmpl.Execute(io.Discard, promptData{}); err != nil {
+			return nil, fmt.Errorf("invalid prompt template: %w", err)
+		}
+		p.source = cfg.Template
+		p.template = tmpl
+	}
+
+	for _, persona := range cfg.Personas {
+		persona.Name = strings.ToLower(strings.TrimSpace(persona.Name))
+		if persona.Instructions == "" {
+			persona.Instructions = builtinPersonas[persona.Name]
+		}
+		if persona.Instructions == "" {
+			return nil, fmt.Errorf("persona %q is not built in and has no instructions", persona.Name)
+		}
+		p.personas = append(p.personas, persona)
+	}
+
+	return p, nil
+}
+
+// personaInstructions returns the instructions of the personas enabled for path.
+func (p *prompts) personaInstructions(path string) string {
+	var lines []string
+	for _, persona := range p.personas {
+		if !personaApplies(persona, path) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- [%s] %s", persona.Name, strings.TrimSpace(persona.Instructions)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func personaApplies(persona Persona, path string) bool {
+	if len(persona.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range persona.Paths {
+		if config.MatchGlob(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const responseFormat = `Respond with a single JSON object and nothing else:
+{"findings": [{
+	"line": <number>,
+	"category": "security|performance|maintainability|error_handling",
+	"description": "<concise issue description>",
+	"severity": "high|medium|low",
+	"suggestion": "<specific improvement suggestion>",
+	"confidence": 0-1
+}]}
+Use {"findings": []} when there is nothing to report.`
+
+// defaultPromptTemplate asks the model to review only the changed code of a file. The hunks
+// are sent with surrounding context and new-file line numbers.
+const defaultPromptTemplate = `You are reviewing a pull request. Below are the changed hunks of the {{.Language}} file {{.Path}}.
+Each line is prefixed with its line number in the new file and a marker:
+"+" for added or modified lines, " " for unchanged context, "-" for removed lines (no new line number).
+
+Diff:
+{{.Diff}}
+{{if .RelatedCode}}{{.RelatedCode}}{{end}}
+{{- if .Personas}}Review focus:
+{{.Personas}}
+
+{{end}}
+{{- if .Guidelines}}Repository guidelines:
+{{.Guidelines}}
+
+{{end -}}
+{{.ResponseFormat}}
+
+Rules:
+1. Only comment on lines marked "+"; the context is there to help you understand them
+2. Use the new-file line number shown in front of the line you are commenting on
+3. Only report issues with confidence >= 0.7
+4. Suggest concrete fixes
+5. Avoid trivial/style-only issues`
+
+// promptData holds the variables available to review prompt templates.
+type promptData struct {
+	Language       string
+	Path           string
+	Diff           string
+	RelatedCode    string // Pre-rendered by formatRelatedCode; may be empty
+	Guidelines     string
+	Personas       string // Instructions of the personas enabled for Path, one per line
+	ResponseFormat string
+}
+
+// buildPrompt renders the review prompt for some hunks of a file. A custom template that fails
+// to render falls back to the built-in one, and the response format is appended when a custom
+// template leaves it out, since the reply parser depends on it.
+func (a *Analyzer) buildPrompt(file *models.File, hunks []*diff.Hunk, related string) string {
+	data := promptData{
+		Language:       languageForPath(file.Path),
+		Path:           file.Path,
+		Diff:           formatHunks(file.Content, hunks, a.config.ContextLines),
+		RelatedCode:    related,
+		Guidelines:     a.prompts.guidelines,
+		Personas:       a.prompts.personaInstructions(file.Path),
+		ResponseFormat: a.responseFormat(),
+	}
+
+	var builder strings.Builder
+	if err := a.prompts.template.Execute(&builder, data); err != nil {
+		log.Printf("Warning: Failed to render prompt template for %s, using the default: %v", file.Path, err)
+		builder.Reset()
+		if err := defaultTemplate.Execute(&builder, data); err != nil {
+			log.Printf("Error rendering default prompt template: %v", err)
+		}
+	}
+
+	prompt := builder.String()
+	if !strings.Contains(prompt, a.responseFormat()) {
+		prompt += "\n\n" + a.responseFormat()
+	}
+	return prompt
+}
+
+// formatRelatedCode renders the snippets, most relevant first, until the next one would not
+// fit in maxTokens. It returns the rendered section and the number of snippets included.
+func formatRelatedCode(snippets []models.CodeSnippet, maxTokens int, estimate func(string) int) (string, int) {
+	if len(snippets) == 0 || maxTokens <= 0 {
+		return "", 0
+	}
+
+	header := "Related code from other files (for reference only, do not review or report issues in it):\n\n"
+	var builder strings.Builder
+	builder.WriteString(header)
+	tokens := estimate(header)
+	included := 0
+	for _, snippet := range snippets {
+		block := fmt.Sprintf("// %s:%d-%d (%s of %s)\n%s\n\n", snippet.Path, snippet.StartLine, snippet.EndLine, snippet.Kind, snippet.Symbol, snippet.Code)
+		blockTokens := estimate(block)
+		if tokens+blockTokens > maxTokens {
+			continue
+		}
+		builder.WriteString(block)
+		tokens += blockTokens
+		included++
+	}
+	if included == 0 {
+		return "", 0
+	}
+	return builder.String(), included
+}
+
+// formatHunks renders hunks with line numbers and up to contextLines extra lines of
+// unchanged code from the file before and after each hunk.
+func formatHunks(content string, hunks []*diff.Hunk, contextLines int) string {
+	fileLines := strings.Split(content, "\n")
+	var builder strings.Builder
+	for _, hunk := range hunks {
+		writeHunk(&builder, fileLines, hunk, contextLines)
+	}
+	return builder.String()
+}
+
+// writeHunk renders one hunk of formatHunks with contextLines of fileLines around it.
+func writeHunk(builder *strings.Builder, fileLines []string, hunk *diff.Hunk, contextLines int) {
+	writeContext := func(from, to int) {
+		for n := from; n <= to; n++ {
+			if n >= 1 && n <= len(fileLines) {
+				builder.WriteString(fmt.Sprintf("%5d   %s\n", n, fileLines[n-1]))
+			}
+		}
+	}
+
+	builder.WriteString(hunk.Header + "\n")
+	writeContext(hunk.NewStart-contextLines, hunk.NewStart-1)
+	for _, line := range hunk.Lines {
+		if line.Kind == '-' {
+			builder.WriteString(fmt.Sprintf("%5s - %s\n", "", line.Content))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%5d %c %s\n", line.NewLine, line.Kind, line.Content))
+	}
+	hunkEnd := hunk.NewStart + hunk.NewLines - 1
+	writeContext(hunkEnd+1, hunkEnd+contextLines)
+	builder.WriteString("\n")
+}
+
+// issuesInHunks drops findings whose line is outside every hunk's new-file range.
+func issuesInHunks(issues []*models.Issue, hunks []*diff.Hunk) []*models.Issue {
+	if len(hunks) == 0 {
+		return issues
+	}
+
+	var kept []*models.Issue
+	for _, issue := range issues {
+		if lineInHunks(issue.Line, hunks) {
+			kept = append(kept, issue)
+		} else {
+			log.Printf("Dropping AI finding outside the diff: %s:%d", issue.Path, issue.Line)
+		}
+	}
+	return kept
+}
+
+func lineInHunks(line int, hunks []*diff.Hunk) bool {
+	for _, hunk := range hunks {
+		if line >= hunk.NewStart && line < hunk.NewStart+hunk.NewLines {
+			return true
+		}
+	}
+	return false
+}
+
+func languageForPath(path string) string {
+	switch filepath.Ext(path) {
+	case ".go":
+		return "Go"
+	case ".js":
+		return "JavaScript"
+	case ".ts":
+		return "TypeScript"
+	case ".py":
+		return "Python"
+	case ".java":
+		return "Java"
+	default:
+		return "source"
+	}
+}
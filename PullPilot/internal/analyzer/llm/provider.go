@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/keploy/PullPilot/internal/config"
+)
+
+// LLMProvider is a chat/completion backend used by the AI analyzer.
+type LLMProvider interface {
+	// Name identifies the backend, e.g. "gemini", "openai" or "ollama".
+	Name() string
+	// Model is the model the provider sends requests to.
+	Model() string
+	// EstimateTokens approximates how many tokens text uses with this provider's tokenizer.
+	EstimateTokens(text string) int
+	// GenerateContent sends a single-turn prompt and returns the model's text reply.
+	GenerateContent(ctx context.Context, prompt string) (string, error)
+}
+
+// StructuredProvider is implemented by providers that can constrain their reply to a JSON
+// schema natively (Gemini responseSchema, OpenAI response_format, Ollama format).
+type StructuredProvider interface {
+	GenerateStructured(ctx context.Context, prompt string, schema map[string]interface{}) (string, error)
+}
+
+// NewProvider selects the LLM backend for cfg.LLMProviderURL. cfg.LLMProvider, when set,
+// overrides the detection. Gemini is used for generativelanguage.googleapis.com, Ollama for
+// ollama:// URLs or the default Ollama port 11434, and any other URL is treated as an
+// OpenAI-compatible chat completions endpoint.
+func NewProvider(cfg *config.Config, aiCfg *AIConfig) (LLMProvider, error) {
+	providerURL := cfg.LLMProviderURL
+	if providerURL == "" {
+		return NewGoogleAIClient(cfg.GoogleAIKey, aiCfg), nil
+	}
+
+	parsed, err := url.Parse(providerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM provider URL %q: %w", providerURL, err)
+	}
+
+	kind := strings.ToLower(cfg.LLMProvider)
+	if kind == "" {
+		switch {
+		case strings.HasSuffix(parsed.Hostname(), "generativelanguage.googleapis.com"):
+			kind = "gemini"
+		case parsed.Scheme == "ollama" || parsed.Port() == "11434":
+			kind = "ollama"
+		default:
+			kind = "openai"
+		}
+	}
+	if parsed.Scheme == "ollama" {
+		parsed.Scheme = "http"
+		providerURL = parsed.String()
+	}
+
+	switch kind {
+	case "gemini":
+		return NewGoogleAIClientWithURL(providerURL, cfg.GoogleAIKey, aiCfg), nil
+	case "ollama":
+		return NewOllamaClient(providerURL, aiCfg), nil
+	case "openai":
+		return NewOpenAIClient(providerURL, cfg.LLMApiKey, aiCfg), nil
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %s", kind)
+	}
+}
+
+func modelOrDefault(cfg *AIConfig, def string) string {
+	if cfg != nil && cfg.Model != "" {
+		return cfg.Model
+	}
+	return def
+}
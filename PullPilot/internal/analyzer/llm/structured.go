@@ -0,0 +1,220 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+var findingCategories = []string{"security", "performance", "maintainability", "error_handling"}
+
+var findingSeverities = []string{"high", "medium", "low"}
+
+// minConfidence is the confidence below which findings are dropped.
+const minConfidence = 0.7
+
+// findingsSchema is the JSON schema every AI reply must satisfy. It is sent to providers with
+// native structured output and mirrored by validateFinding for the rest.
+var findingsSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"findings": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"line":        map[string]interface{}{"type": "integer"},
+					"category":    map[string]interface{}{"type": "string", "enum": findingCategories},
+					"description": map[string]interface{}{"type": "string"},
+					"severity":    map[string]interface{}{"type": "string", "enum": findingSeverities},
+					"suggestion":  map[string]interface{}{"type": "string"},
+					"confidence":  map[string]interface{}{"type": "number"},
+				},
+				"required":             []string{"line", "category", "description", "severity", "suggestion", "confidence"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required":             []string{"findings"},
+	"additionalProperties": false,
+}
+
+type aiFinding struct {
+	Line        *int     `json:"line"`
+	Category    *string  `json:"category"`
+	Description *string  `json:"description"`
+	Severity    *string  `json:"severity"`
+	Suggestion  *string  `json:"suggestion"`
+	Confidence  *float64 `json:"confidence"`
+	Fix         *aiFix   `json:"fix,omitempty"` // Only requested when AIConfig.SuggestFixes is set
+}
+
+type aiFindings struct {
+	Findings *[]aiFinding `json:"findings"`
+}
+
+// Process-wide parse counters, reported by ParseMetrics.
+var (
+	responsesParsed  int64
+	parseFailures    int64
+	repairsAttempted int64
+	repairsSucceeded int64
+)
+
+// ParseMetrics reports how often AI replies failed schema validation since startup.
+func ParseMetrics() map[string]int64 {
+	return map[string]int64{
+		"responses":         atomic.LoadInt64(&responsesParsed),
+		"parse_failures":    atomic.LoadInt64(&parseFailures),
+		"repairs_attempted": atomic.LoadInt64(&repairsAttempted),
+		"repairs_succeeded": atomic.LoadInt64(&repairsSucceeded),
+	}
+}
+
+// parseFindings validates a reply against findingsSchema. An invalid reply gets a single
+// repair round-trip in which the model is shown the error and asked to fix its JSON.
+func (a *Analyzer) parseFindings(ctx context.Context, response string, stats *models.AIStats) ([]aiFinding, error) {
+	atomic.AddInt64(&responsesParsed, 1)
+	stats.Responses++
+
+	findings, err := decodeFindings(response)
+	if err == nil {
+		return findings, nil
+	}
+
+	fmt.Println("AI response failed schema validation, requesting a repair:", err)
+	atomic.AddInt64(&parseFailures, 1)
+	atomic.AddInt64(&repairsAttempted, 1)
+	stats.ParseFailures++
+	stats.RepairAttempts++
+
+	repaired, repairErr := a.generate(ctx, "repair", buildRepairPrompt(response, err, a.findingsSchema()), a.findingsSchema(), stats)
+	if repairErr != nil {
+		return nil, fmt.Errorf("invalid AI response (%v) and repair request failed: %w", err, repairErr)
+	}
+
+	findings, err = decodeFindings(repaired)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AI response after repair: %w", err)
+	}
+	atomic.AddInt64(&repairsSucceeded, 1)
+	stats.RepairSuccesses++
+	return findings, nil
+}
+
+func buildRepairPrompt(response string, validationErr error, findingsSchema map[string]interface{}) string {
+	schema, _ := json.MarshalIndent(findingsSchema, "", "  ")
+	return fmt.Sprintf(`Your previous reply was not valid JSON for the required schema.
+
+Error: %s
+
+Previous reply:
+%s
+
+Return only the corrected JSON object, without code fences or commentary, matching this JSON schema:
+%s`, validationErr, response, schema)
+}
+
+// decodeFindings strictly decodes a reply into findings. Markdown code fences around the JSON
+// are tolerated; anything else that does not match the schema is an error.
+func decodeFindings(response string) ([]aiFinding, error) {
+	body := strings.TrimSpace(response)
+	if strings.HasPrefix(body, "```") {
+		body = strings.TrimPrefix(body, "```json")
+		body = strings.TrimPrefix(body, "```")
+		body = strings.TrimSuffix(strings.TrimSpace(body), "```")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(body)))
+	decoder.DisallowUnknownFields()
+
+	var result aiFindings
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON object")
+	}
+	if result.Findings == nil {
+		return nil, errors.New(`missing required field "findings"`)
+	}
+
+	for i, finding := range *result.Findings {
+		if err := validateFinding(finding); err != nil {
+			return nil, fmt.Errorf("findings[%d]: %w", i, err)
+		}
+	}
+	return *result.Findings, nil
+}
+
+func validateFinding(f aiFinding) error {
+	switch {
+	case f.Line == nil:
+		return errors.New(`missing required field "line"`)
+	case f.Category == nil:
+		return errors.New(`missing required field "category"`)
+	case f.Description == nil:
+		return errors.New(`missing required field "description"`)
+	case f.Severity == nil:
+		return errors.New(`missing required field "severity"`)
+	case f.Suggestion == nil:
+		return errors.New(`missing required field "suggestion"`)
+	case f.Confidence == nil:
+		return errors.New(`missing required field "confidence"`)
+	}
+
+	if *f.Line < 1 {
+		return fmt.Errorf("line must be >= 1, got %d", *f.Line)
+	}
+	if !containsString(findingCategories, *f.Category) {
+		return fmt.Errorf("category %q is not one of %v", *f.Category, findingCategories)
+	}
+	if !containsString(findingSeverities, *f.Severity) {
+		return fmt.Errorf("severity %q is not one of %v", *f.Severity, findingSeverities)
+	}
+	if *f.Confidence < 0 || *f.Confidence > 1 {
+		return fmt.Errorf("confidence must be between 0 and 1, got %v", *f.Confidence)
+	}
+	if strings.TrimSpace(*f.Description) == "" {
+		return errors.New("description must not be empty")
+	}
+	return nil
+}
+
+func findingsToIssues(findings []aiFinding, filePath string) []*models.Issue {
+	var issues []*models.Issue
+
+	for _, f := range findings {
+		if *f.Confidence < minConfidence {
+			continue
+		}
+
+		issues = append(issues, &models.Issue{
+			Path:        filePath,
+			Line:        *f.Line,
+			Title:       fmt.Sprintf("[%s] %s", strings.ToUpper(*f.Category), *f.Description),
+			Description: *f.Description,
+			Severity:    models.ParseSeverityOrDefault(*f.Severity, models.SeverityInfo),
+			Suggestion:  *f.Suggestion,
+			Source:      "AI Analysis",
+			Category:    *f.Category,
+		})
+	}
+
+	return issues
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+var summarySchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"overview": map[string]interface{}{"type": "string"},
+		"files": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path":    map[string]interface{}{"type": "string"},
+					"summary": map[string]interface{}{"type": "string"},
+				},
+				"required":             []string{"path", "summary"},
+				"additionalProperties": false,
+			},
+		},
+		"risk_areas":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"review_order": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+	},
+	"required":             []string{"overview", "files", "risk_areas", "review_order"},
+	"additionalProperties": false,
+}
+
+// SummarizePR asks the model for a PR walkthrough and records its usage in stats when non-nil.
+func (a *Analyzer) SummarizePR(ctx context.Context, files []*models.File, stats *models.AIStats) (*models.PRSummary, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no changed files to summarize")
+	}
+
+	response, err := a.generate(ctx, "summary", a.buildSummaryPrompt(files), summarySchema, stats)
+	if err != nil {
+		return nil, fmt.Errorf("summary request failed: %w", err)
+	}
+
+	body := strings.TrimSpace(response)
+	body = strings.TrimPrefix(body, "```json")
+	body = strings.TrimPrefix(body, "```")
+	body = strings.TrimSuffix(strings.TrimSpace(body), "```")
+
+	var summary models.PRSummary
+	if err := json.Unmarshal([]byte(body), &summary); err != nil {
+		return nil, fmt.Errorf("invalid summary JSON: %w", err)
+	}
+	if strings.TrimSpace(summary.Overview) == "" {
+		return nil, fmt.Errorf("summary has no overview")
+	}
+	return &summary, nil
+}
+
+// buildSummaryPrompt includes patches largest first up to the token limit and lists the rest by name.
+func (a *Analyzer) buildSummaryPrompt(files []*models.File) string {
+	sorted := make([]*models.File, len(files))
+	copy(sorted, files)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return diffSize(diff.ParsePatch(sorted[i].Patch)) > diffSize(diff.ParsePatch(sorted[j].Patch))
+	})
+
+	var included, omitted strings.Builder
+	used := 0
+	for _, file := range sorted {
+		section := fmt.Sprintf("### %s (%s)\n```diff\n%s\n```\n\n", file.Path, file.Status, file.Patch)
+		tokens := a.provider.EstimateTokens(section)
+		if file.Patch == "" || (a.config.MaxPromptTokens > 0 && used+tokens > a.config.MaxPromptTokens*3/4) {
+			omitted.WriteString(fmt.Sprintf("- %s (%s)\n", file.Path, file.Status))
+			continue
+		}
+		used += tokens
+		included.WriteString(section)
+	}
+
+	prompt := fmt.Sprintf(`Summarize this pull request for its reviewers.
+
+Changes:
+%s`, included.String())
+	if omitted.Len() > 0 {
+		prompt += fmt.Sprintf("\nOther changed files (diff not shown):\n%s", omitted.String())
+	}
+
+	return prompt + `
+Respond with a single JSON object and nothing else:
+{
+	"overview": "<2-4 sentences on what changed and why>",
+	"files": [{"path": "<file path>", "summary": "<one line>"}],
+	"risk_areas": ["<area that deserves careful review, and why>"],
+	"review_order": ["<file path, in the order a reviewer should read them>"]
+}
+
+Rules:
+1. Include every changed file in "files" and "review_order"
+2. Keep per-file summaries to one line
+3. Only list real risks (behavior changes, security, data migrations, concurrency, public API changes)`
+}
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// modelPrices are list prices in USD per million prompt and completion tokens, used when
+// AIConfig does not set prices. Models are matched by prefix, longest first.
+var modelPrices = map[string][2]float64{
+	"gemini-2.0-flash-lite": {0.075, 0.30},
+	"gemini-2.0-flash":      {0.10, 0.40},
+	"gemini-1.5-flash":      {0.075, 0.30},
+	"gemini-1.5-pro":        {1.25, 5.00},
+	"gemini-2.5-flash":      {0.30, 2.50},
+	"gemini-2.5-pro":        {1.25, 10.00},
+	"gpt-4o-mini":           {0.15, 0.60},
+	"gpt-4o":                {2.50, 10.00},
+	"gpt-4.1-mini":          {0.40, 1.60},
+	"gpt-4.1":               {2.00, 8.00},
+}
+
+type usageKey struct{}
+
+// reportedUsage carries the token counts a provider read from its response back to generate.
+type reportedUsage struct {
+	promptTokens     int
+	completionTokens int
+	reported         bool
+}
+
+func withUsage(ctx context.Context) (context.Context, *reportedUsage) {
+	usage := &reportedUsage{}
+	return context.WithValue(ctx, usageKey{}, usage), usage
+}
+
+// reportUsage is called by providers with the usage metadata of a response, if it has any.
+func reportUsage(ctx context.Context, promptTokens, completionTokens int) {
+	if usage, ok := ctx.Value(usageKey{}).(*reportedUsage); ok && (promptTokens > 0 || completionTokens > 0) {
+		usage.promptTokens, usage.completionTokens, usage.reported = promptTokens, completionTokens, true
+	}
+}
+
+// recordCall builds the LLMCall of a finished request and adds it to stats. Token counts fall
+// back to estimates when the provider did not report usage.
+func (a *Analyzer) recordCall(stats *models.AIStats, purpose, prompt, response string, usage *reportedUsage, started time.Time) {
+	if stats == nil {
+		return
+	}
+
+	call := models.LLMCall{
+		Provider:         a.provider.Name(),
+		Model:            a.provider.Model(),
+		Purpose:          purpose,
+		PromptTokens:     usage.promptTokens,
+		CompletionTokens: usage.completionTokens,
+		LatencyMS:        time.Since(started).Milliseconds(),
+		At:               started,
+	}
+	if !usage.reported {
+		call.PromptTokens = a.provider.EstimateTokens(prompt)
+		call.CompletionTokens = a.provider.EstimateTokens(response)
+		call.Estimated = true
+	}
+
+	promptPrice, completionPrice := a.prices(call.Model)
+	call.CostUSD = (float64(call.PromptTokens)*promptPrice + float64(call.CompletionTokens)*completionPrice) / 1e6
+
+	stats.Calls = append(stats.Calls, call)
+	stats.Usage.Add(call)
+}
+
+// prices returns the USD price per million prompt and completion tokens of a model.
+func (a *Analyzer) prices(model string) (float64, float64) {
+	if a.config.PromptPricePerMTok > 0 || a.config.CompletionPricePerMTok > 0 {
+		return a.config.PromptPricePerMTok, a.config.CompletionPricePerMTok
+	}
+	if a.provider.Name() == "ollama" {
+		return 0, 0
+	}
+
+	best := ""
+	for prefix := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return 0, 0
+	}
+	return modelPrices[best][0], modelPrices[best][1]
+}
@@ -6,16 +6,20 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/keploy/PullPilot/internal/analyzer/custom"
 	"github.com/keploy/PullPilot/internal/analyzer/dependency"
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
 	"github.com/keploy/PullPilot/internal/analyzer/llm"
 	"github.com/keploy/PullPilot/internal/analyzer/static"
+	"github.com/keploy/PullPilot/internal/analyzer/symbols"
 	"github.com/keploy/PullPilot/internal/config"
 	"github.com/keploy/PullPilot/internal/formatter"
+	"github.com/keploy/PullPilot/internal/httpclient"
 	"github.com/keploy/PullPilot/internal/reporter"
 	"github.com/keploy/PullPilot/internal/shared"
 	"github.com/keploy/PullPilot/pkg/github"
@@ -23,7 +27,6 @@ import (
 )
 
 var pullnumber int
-var AllIssues []*models.Issue
 
 func PullRequestNumber(currentpullnumber int) int {
 	pullnumber = currentpullnumber
@@ -35,6 +38,18 @@ type Job struct {
 	RepoOwner string
 	RepoName  string
 	PRNumber  int
+
+	// InstallationID is the GitHub App installation a webhook came from. Runs without one
+	// use GITHUB_INSTALLATION_ID or look the installation up by repository.
+	InstallationID int64
+
+	client      *github.Client // Resolved for the job by AnalyzeCode
+	checkoutDir string         // Local checkout of the repository, see config.CheckoutDir
+
+	// repoConfig is the trusted .pullpilot.yml of the job, see trustedRepoConfig. It is nil
+	// with repoConfigErr set when it could not be loaded.
+	repoConfig    *config.RepoConfig
+	repoConfigErr error
 }
 
 type Orchestrator struct {
@@ -42,25 +57,98 @@ type Orchestrator struct {
 	staticAnalyzer *static.Linter
 	depAnalyzer    *dependency.Scanner
 	customAnalyzer *custom.Rules
-	aiAnalyzer     *llm.GoogleAIClient
+	aiAnalyzer     *llm.Analyzer
 	githubClient   *github.Client
+	githubApp      *github.AppAuth
+
+	clientsMu  sync.Mutex
+	appClients map[int64]*github.Client // Per installation
 }
 
 func NewOrchestrator(cfg *config.Config) *Orchestrator {
+	httpclient.SetDefaultPolicy(httpclient.Policy{
+		MaxRetries: cfg.HTTPMaxRetries,
+		BaseDelay:  cfg.HTTPRetryBaseDelay,
+		MaxDelay:   cfg.HTTPRetryMaxDelay,
+	})
+	httpclient.SetLimit("github", cfg.GitHubMaxConcurrency, cfg.GitHubRequestsPerSecond)
+	httpclient.SetLimit("deps.dev", cfg.DepsDevMaxConcurrency, cfg.DepsDevRequestsPerSecond)
+
+	github.SetEndpoints(github.Endpoints{
+		APIURL: cfg.GitHubAPIURL,
+		RawURL: cfg.GitHubRawURL,
+	})
+	if cfg.GitHubCABundle != "" {
+		if err := httpclient.SetCABundle("github", cfg.GitHubCABundle); err != nil {
+			log.Printf("Warning: GitHub CA bundle not loaded: %v", err)
+		}
+	}
+
+	shared.SetRunRetention(cfg.MaxStoredRuns, cfg.StoredRunTTL)
+	if cfg.UsageStorePath != "" {
+		if err := shared.LoadUsage(cfg.UsageStorePath); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
 	aiConfig := &llm.AIConfig{
+		Model:       cfg.AIModel,
 		MaxTokens:   cfg.AIMaxTokens,
 		Temperature: cfg.AITemperature,
-		MinSeverity: models.SeverityInfo,
+		MinSeverity: cfg.AIMinSeverity,
+
+		ContextLines:     cfg.AIContextLines,
+		MaxFileSizeBytes: cfg.MaxFileSizeBytes,
+		MaxPromptTokens:  cfg.AIMaxPromptTokens,
+		TokenBudget:      cfg.AITokenBudget,
+		StructuredOutput: cfg.AIStructuredOutput,
+
+		ContextTokenBudget: cfg.AIContextTokenBudget,
+		Workers:            cfg.AIWorkers,
+		SuggestFixes:       cfg.AISuggestFixes,
+		GroundFindings:     cfg.AIGroundFindings,
+		GroundingWindow:    cfg.AIGroundingWindow,
+		GroundingMode:      cfg.AIGroundingMode,
+
+		PromptPricePerMTok:     cfg.AIPromptPricePerMTok,
+		CompletionPricePerMTok: cfg.AICompletionPricePerMTok,
 	}
 
-	return &Orchestrator{
+	o := &Orchestrator{
 		cfg:            cfg,
 		staticAnalyzer: static.NewLinter(cfg),
 		depAnalyzer:    dependency.NewScanner(cfg),
 		customAnalyzer: custom.NewRules(cfg),
 		githubClient:   github.NewClient(cfg.GitHubToken),
-		aiAnalyzer:     llm.NewGoogleAIClient(cfg.GoogleAIKey, aiConfig),
+		appClients:     make(map[int64]*github.Client),
+	}
+	if cfg.GitHubAppConfigured() {
+		app, err := github.NewAppAuth(cfg.GitHubAppID, []byte(cfg.GitHubAppPrivateKey))
+		if err != nil {
+			log.Printf("Warning: GitHub App authentication disabled: %v", err)
+		} else {
+			o.githubApp = app
+		}
+	}
+
+	provider, err := llm.NewProvider(cfg, aiConfig)
+	if err != nil {
+		log.Printf("Warning: AI analysis disabled: %v", err)
+		return o
+	}
+	log.Printf("Using LLM provider %s (model %s)", provider.Name(), provider.Model())
+	httpclient.SetLimit(provider.Name(), cfg.LLMMaxConcurrency, cfg.LLMRequestsPerSecond)
+	o.aiAnalyzer = llm.NewAnalyzer(provider, aiConfig)
+	if cfg.EnableAICache {
+		cache, err := llm.NewResponseCache(cfg.AICacheDir, cfg.AICacheTTL, cfg.AICacheMaxBytes)
+		if err != nil {
+			log.Printf("Warning: AI response cache disabled: %v", err)
+		} else {
+			o.aiAnalyzer.SetCache(cache)
+		}
 	}
+
+	return o
 }
 func extractPullNumber(PullRequest_url string) string {
 	if PullRequest_url == "" {
@@ -89,39 +177,51 @@ func extractOwnerAndRepo(PullRequest_url string) (string, string, error) {
 	return owner, repo, nil
 }
 func (o *Orchestrator) AnalyzeCode(job *Job) ([]*models.Issue, error) {
-	PullRequest_url := os.Getenv("PULL_REQUEST_URL")
-	repoOwner, repoName, err := extractOwnerAndRepo(PullRequest_url)
-	if err != nil {
-		log.Fatalf("could not extract owner and repo from the URL: %v", err)
-	}
-	pull_number := extractPullNumber(PullRequest_url)
-	if pull_number == "" {
-		log.Fatal("could not extract pull number from the URL")
-	}
-
-	// pullRequestNumber, err := strconv.Atoi(pull_number)
-	// if err != nil {
-	// 	log.Fatalf("failed to convert pull number to integer: %v", err)
-	// }
-	githubToken := os.Getenv("GITHUB_TOKEN")
-	if githubToken == "" {
-		log.Fatal("GITHUB_TOKEN environment variable is not set.")
+	// Jobs from the Action carry no repository; it comes from PULL_REQUEST_URL instead.
+	if job.RepoOwner == "" || job.RepoName == "" || job.PRNumber == 0 {
+		PullRequest_url := os.Getenv("PULL_REQUEST_URL")
+		repoOwner, repoName, err := extractOwnerAndRepo(PullRequest_url)
+		if err != nil {
+			return nil, fmt.Errorf("could not extract owner and repo from the URL: %w", err)
+		}
+		prNumber, err := strconv.Atoi(extractPullNumber(PullRequest_url))
+		if err != nil {
+			return nil, fmt.Errorf("could not extract pull number from the URL: %w", err)
+		}
+		job.RepoOwner, job.RepoName, job.PRNumber = repoOwner, repoName, prNumber
 	}
-
-	if repoOwner == "" || repoName == "" {
-		log.Fatal("Could not determine repository owner/name (set GITHUB_REPOSITORY_OWNER/GITHUB_REPOSITORY or ensure GITHUB_REPOSITORY is 'owner/repo').")
+	if o.githubApp == nil && o.cfg.GitHubToken == "" {
+		return nil, errors.New("GITHUB_TOKEN is not set and no GitHub App is configured")
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute) // Longer timeout for API calls
 	defer cancel()
 	// diffContent, changedFilesContent, err := diff.GetDiffAndContentFromPR(ctx, repoOwner, repoName, pullRequestNumber, githubToken)
 
-	AllIssues = []*models.Issue{}
+	job.client = o.clientFor(ctx, job)
+	job.checkoutDir = o.cfg.CheckoutDir(job.RepoOwner, job.RepoName)
+
+	pr := o.fetchPullRequest(ctx, job)
+	job.repoConfig, job.repoConfigErr = o.trustedRepoConfig(ctx, job, pr)
+	if job.repoConfigErr != nil {
+		log.Printf("Warning: Ignoring repository config: %v", job.repoConfigErr)
+	}
+	checkRunID := o.createCheckRun(ctx, job, pr)
 
 	files, err := o.fetchChangedFiles(ctx, job)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch changed files: %w", err)
+		err = fmt.Errorf("failed to fetch changed files: %w", err)
+		o.failCheckRun(job, checkRunID, err)
+		return nil, err
+	}
+	run := &shared.Run{
+		ID:        fmt.Sprintf("%s-%s-%d-%d", job.RepoOwner, job.RepoName, job.PRNumber, time.Now().UnixNano()),
+		RepoOwner: job.RepoOwner,
+		RepoName:  job.RepoName,
+		PRNumber:  job.PRNumber,
+		StartedAt: time.Now(),
 	}
+	o.startCheckRun(ctx, job, checkRunID)
 	resultsCh := make(chan *models.Issue)
 	var wg sync.WaitGroup
 
@@ -145,12 +245,20 @@ func (o *Orchestrator) AnalyzeCode(job *Job) ([]*models.Issue, error) {
 		}()
 	}
 
-	if o.cfg.EnableAI {
+	var aiStats *models.AIStats
+	var aiAnalyzer *llm.Analyzer
+	aiEnabled := o.cfg.EnableAI && o.aiAnalyzer != nil
+	budgetExceeded := aiEnabled && o.budgetExceeded()
+	aiEnabled = aiEnabled && !budgetExceeded
+	if aiEnabled {
+		aiAnalyzer = o.runAIAnalyzer(job)
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			o.runAnalyzer("AI", func() ([]*models.Issue, error) {
-				return o.aiAnalyzer.AnalyzeCode(ctx, files)
+				issues, stats, err := aiAnalyzer.AnalyzeCodeWithStats(ctx, files)
+				aiStats = stats
+				return issues, err
 			}, resultsCh)
 		}()
 	}
@@ -158,36 +266,174 @@ func (o *Orchestrator) AnalyzeCode(job *Job) ([]*models.Issue, error) {
 	go func() {
 		defer wg.Done()
 		o.runAnalyzer("Custom", func() ([]*models.Issue, error) {
-			return o.customAnalyzer.Analyze(ctx, files)
+			return o.customAnalyzer.Analyze(ctx, job.checkoutDir, files)
 		}, resultsCh)
 	}()
 	go func() {
 		wg.Wait()
 		close(resultsCh)
 	}()
+	allIssues := []*models.Issue{}
 	for issue := range resultsCh {
-		AllIssues = append(AllIssues, issue)
+		if !issue.Severity.AtLeast(o.cfg.MinSeverity) {
+			continue
+		}
+		allIssues = append(allIssues, issue)
 	}
-	comments := o.prepareComments(AllIssues)
-	if err := o.sendReviewComment(ctx, job, comments); err != nil {
+	if aiEnabled && o.cfg.EnablePRSummary {
+		if aiStats == nil {
+			aiStats = &models.AIStats{}
+		}
+		o.postSummary(ctx, job, aiAnalyzer, files, aiStats)
+	}
+
+	comments := o.prepareComments(allIssues)
+	if err := o.sendReviewComment(ctx, job, pr, files, comments); err != nil {
 		log.Printf("Warning: Failed to send review comments: %v", err)
 	}
 
-	for _, issue := range AllIssues {
+	for _, issue := range allIssues {
 		if err := shared.AddIssue(issue); err != nil {
 			log.Printf("Warning: Failed to add issue to shared storage: %v", err)
 		}
 	}
 
 	log.Printf("Analysis completed for %s/%s PR #%d with %d issues",
-		job.RepoOwner, job.RepoName, job.PRNumber, len(AllIssues))
-	report := reporter.GenerateMarkdownReport(AllIssues)
+		job.RepoOwner, job.RepoName, job.PRNumber, len(allIssues))
+	report := reporter.GenerateMarkdownReport(allIssues)
+
+	if o.cfg.EnableDependencyCheck && o.cfg.EnableSBOM {
+		sbom, err := o.depAnalyzer.GenerateSBOM(job.RepoOwner+"/"+job.RepoName, job.checkoutDir, files)
+		if err != nil {
+			log.Printf("Warning: Failed to generate SBOM: %v", err)
+		} else {
+			run.CycloneDXSBOM = sbom.CycloneDX
+			run.SPDXSBOM = sbom.SPDX
+			run.SBOMDiff = sbom.Diff
+			report += reporter.GenerateDependencyDiffMarkdown(sbom.Diff)
+		}
+	}
+
+	report += reporter.GenerateAIStatsMarkdown(aiStats)
+	if budgetExceeded {
+		report += fmt.Sprintf("\n_AI review skipped: the monthly LLM budget of $%.2f has been reached._\n", o.cfg.AIMonthlyBudgetUSD)
+	}
+	if aiStats != nil {
+		shared.RecordUsage(job.RepoOwner+"/"+job.RepoName, aiStats.Calls)
+	}
+
+	run.Gate = o.evaluateGate(ctx, job, pr, files, allIssues, checkRunID != 0)
+	report += reporter.GenerateGateMarkdown(run.Gate)
+
+	run.Issues = allIssues
+	run.AIStats = aiStats
+	run.CompletedAt = time.Now()
+	shared.SaveRun(run)
+	log.Printf("Run %s recorded", run.ID)
+
+	o.completeCheckRun(job, checkRunID, run.Gate, report, allIssues)
 
 	if err := o.saveReport(report); err != nil {
 		log.Printf("Failed to save report: %v", err)
 	}
-	return AllIssues, nil
+	return allIssues, nil
+}
+
+// runAIAnalyzer returns the AI analyzer for the run of job, with its own review prompt and
+// symbol index. When the prompt config is invalid the built-in prompt is used.
+func (o *Orchestrator) runAIAnalyzer(job *Job) *llm.Analyzer {
+	var contextProvider llm.ContextProvider
+	if job.checkoutDir != "" {
+		contextProvider = symbols.NewBuilder(job.checkoutDir)
+	}
+
+	analyzer, err := o.aiAnalyzer.ForRun(o.promptConfig(job), contextProvider)
+	if err != nil {
+		log.Printf("Warning: Using the built-in review prompt: %v", err)
+		analyzer, _ = o.aiAnalyzer.ForRun(&llm.PromptConfig{}, contextProvider)
+	}
+	return analyzer
+}
+
+// promptConfig returns the review prompt template, guidelines and personas of the server
+// config, overridden by the review section of the trusted .pullpilot.yml. The review prompt
+// steers what the reviewer reports, so it is never taken from the pull request itself.
+func (o *Orchestrator) promptConfig(job *Job) *llm.PromptConfig {
+	promptConfig := &llm.PromptConfig{}
+
+	if o.cfg.AIPromptTemplateFile != "" {
+		data, err := os.ReadFile(o.cfg.AIPromptTemplateFile)
+		if err != nil {
+			log.Printf("Warning: Failed to read prompt template: %v", err)
+		}
+		promptConfig.Template = string(data)
+	}
+	if o.cfg.AIGuidelinesFile != "" {
+		data, err := os.ReadFile(o.cfg.AIGuidelinesFile)
+		if err != nil {
+			log.Printf("Warning: Failed to read review guidelines: %v", err)
+		}
+		promptConfig.Guidelines = string(data)
+	}
+	for _, name := range o.cfg.AIPersonas {
+		promptConfig.Personas = append(promptConfig.Personas, llm.Persona{Name: name})
+	}
+
+	if job.repoConfig == nil {
+		return promptConfig
+	}
+	review := job.repoConfig.Review
+	if review.PromptTemplate != "" {
+		promptConfig.Template = review.PromptTemplate
+	}
+	if review.Guidelines != "" {
+		promptConfig.Guidelines = review.Guidelines
+	}
+	for _, persona := range review.Personas {
+		promptConfig.Personas = append(promptConfig.Personas, llm.Persona{
+			Name:         persona.Name,
+			Paths:        persona.Paths,
+			Instructions: persona.Instructions,
+		})
+	}
+	return promptConfig
+}
+
+// budgetExceeded reports whether this month's LLM spend has reached the configured budget.
+func (o *Orchestrator) budgetExceeded() bool {
+	if o.cfg.AIMonthlyBudgetUSD <= 0 {
+		return false
+	}
+	spent := shared.MonthToDateCost(time.Now())
+	if spent < o.cfg.AIMonthlyBudgetUSD {
+		return false
+	}
+	log.Printf("AI review disabled: month-to-date LLM spend $%.4f has reached the budget of $%.2f", spent, o.cfg.AIMonthlyBudgetUSD)
+	return true
+}
+
+// postSummary posts the AI walkthrough as a sticky PR comment, updating it on later pushes.
+// The summary call is recorded in stats, so the optional usage footer covers the whole run.
+func (o *Orchestrator) postSummary(ctx context.Context, job *Job, aiAnalyzer *llm.Analyzer, files []*models.File, stats *models.AIStats) {
+	if job.Provider != "github" {
+		return
+	}
+
+	summary, err := aiAnalyzer.SummarizePR(ctx, files, stats)
+	if err != nil {
+		log.Printf("Warning: Failed to generate PR summary: %v", err)
+		return
+	}
+
+	body := reporter.GeneratePRSummaryMarkdown(summary)
+	if o.cfg.AIUsageFooter {
+		body += reporter.GenerateUsageFooter(stats.Usage)
+	}
+	if err := job.client.UpsertStickyComment(ctx, job.RepoOwner, job.RepoName, job.PRNumber, reporter.SummaryMarker, body); err != nil {
+		log.Printf("Warning: Failed to post PR summary: %v", err)
+	}
 }
+
 func (o *Orchestrator) saveReport(report string) error {
 	filename := "code-analysis-report.md"
 	if o.cfg.ReportPath != "" {
@@ -223,21 +469,113 @@ func (o *Orchestrator) prepareComments(issues []*models.Issue) []*models.ReviewC
 
 func (o *Orchestrator) fetchChangedFiles(ctx context.Context, job *Job) ([]*models.File, error) {
 	if job.Provider == "github" {
-		return o.githubClient.GetChangedFiles(ctx, job.RepoOwner, job.RepoName, job.PRNumber)
+		return job.client.GetChangedFiles(ctx, job.RepoOwner, job.RepoName, job.PRNumber)
 	}
 	return nil, fmt.Errorf("unsupported provider: %s", job.Provider)
 }
 
-func (o *Orchestrator) sendReviewComment(ctx context.Context, job *Job, comments []*models.ReviewComment) error {
+// sendReviewComment posts the comments as one PR review. Comments on lines of the diff are
+// placed inline, so that suggestion blocks can be applied from the PR; GitHub rejects inline
+// comments elsewhere, so the rest are listed in the review body.
+func (o *Orchestrator) sendReviewComment(ctx context.Context, job *Job, pr *github.PullRequest, files []*models.File, comments []*models.ReviewComment) error {
 	if job.Provider != "github" {
 		return fmt.Errorf("unsupported provider: %s", job.Provider)
 	}
 
 	if len(comments) > 0 {
-		if err := o.githubClient.CreateReview(ctx, job.RepoOwner, job.RepoName, job.PRNumber, comments); err != nil {
+		commentable := commentableLines(files)
+		var inline, outside []*models.ReviewComment
+		for _, comment := range comments {
+			if onDiff(commentable[comment.Path], comment) {
+				inline = append(inline, comment)
+			} else {
+				outside = append(outside, comment)
+			}
+		}
+
+		commitID := ""
+		if pr != nil {
+			commitID = pr.Head.SHA
+		}
+		if err := job.client.CreateReview(ctx, job.RepoOwner, job.RepoName, job.PRNumber, commitID, reviewBody(outside), inline); err != nil {
 			return fmt.Errorf("failed to create review: %w", err)
 		}
 	}
 
-	return o.githubClient.ProcessPullRequestReview(ctx, job.RepoOwner, job.RepoName, job.PRNumber)
+	return job.client.ProcessPullRequestReview(ctx, job.RepoOwner, job.RepoName, job.PRNumber)
+}
+
+// commentableLines returns, per file, the new-file lines of the diff: added and context lines.
+func commentableLines(files []*models.File) map[string]map[int]bool {
+	lines := make(map[string]map[int]bool, len(files))
+	for _, file := range files {
+		set := make(map[int]bool)
+		for _, hunk := range diff.ParsePatch(file.Patch) {
+			for _, line := range hunk.Lines {
+				if line.NewLine > 0 {
+					set[line.NewLine] = true
+				}
+			}
+		}
+		lines[file.Path] = set
+	}
+	return lines
+}
+
+// onDiff reports whether every line a comment spans is on the diff.
+func onDiff(lines map[int]bool, comment *models.ReviewComment) bool {
+	start := comment.StartLine
+	if start == 0 || start > comment.Line {
+		start = comment.Line
+	}
+	for line := start; line <= comment.Line; line++ {
+		if !lines[line] {
+			return false
+		}
+	}
+	return comment.Line > 0
+}
+
+func reviewBody(outside []*models.ReviewComment) string {
+	body := "### 📝 Automated Review Comments\n\n"
+	if len(outside) == 0 {
+		return body + "Thank you for raising this pull request. The review comments are inline.\n"
+	}
+
+	body += "Thank you for raising this pull request. These comments are on lines outside the diff:\n\n"
+	for _, comment := range outside {
+		body += fmt.Sprintf("- **File:** %s\n  - **Line:** %d\n  - **Comment:** %s\n\n",
+			comment.Path, comment.Line, comment.Body)
+	}
+	return body
+}
+
+// clientFor returns the GitHub client of a job: the App installation's when a GitHub App is
+// configured, otherwise the one using GITHUB_TOKEN.
+func (o *Orchestrator) clientFor(ctx context.Context, job *Job) *github.Client {
+	if o.githubApp == nil || job.Provider != "github" {
+		return o.githubClient
+	}
+
+	installationID := job.InstallationID
+	if installationID == 0 {
+		installationID = o.cfg.GitHubInstallationID
+	}
+	if installationID == 0 {
+		id, err := o.githubApp.InstallationForRepo(ctx, job.RepoOwner, job.RepoName)
+		if err != nil {
+			log.Printf("Warning: Falling back to GITHUB_TOKEN: %v", err)
+			return o.githubClient
+		}
+		installationID = id
+	}
+
+	o.clientsMu.Lock()
+	defer o.clientsMu.Unlock()
+	client, ok := o.appClients[installationID]
+	if !ok {
+		client = github.NewAppClient(o.githubApp, installationID)
+		o.appClients[installationID] = client
+	}
+	return client
 }
@@ -0,0 +1,37 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/keploy/PullPilot/internal/config"
+	"github.com/keploy/PullPilot/pkg/github"
+)
+
+const repoConfigName = ".pullpilot.yml"
+
+// trustedRepoConfig returns the .pullpilot.yml that settings with authority over a run are read
+// from, such as the merge gate. A pull request must not be able to change them for itself, so
+// this is PULLPILOT_CONFIG when set on the server, otherwise the file on the base commit of the
+// pull request and never the one in the checkout of its head.
+func (o *Orchestrator) trustedRepoConfig(ctx context.Context, job *Job, pr *github.PullRequest) (*config.RepoConfig, error) {
+	if o.cfg.RepoConfigPath != "" {
+		return config.LoadRepoConfig(o.cfg.RepoConfigPath)
+	}
+	if job.Provider != "github" {
+		return &config.RepoConfig{}, nil
+	}
+	if pr == nil || pr.Base.SHA == "" {
+		return nil, errors.New("the base commit of the pull request is unknown")
+	}
+
+	data, err := job.client.GetFileContent(ctx, job.RepoOwner, job.RepoName, repoConfigName, pr.Base.SHA)
+	if errors.Is(err, github.ErrNotFound) {
+		return &config.RepoConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from the base branch: %w", repoConfigName, err)
+	}
+	return config.ParseRepoConfig(data, repoConfigName+" on "+pr.Base.Ref)
+}
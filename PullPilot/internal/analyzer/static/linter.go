@@ -194,7 +194,7 @@ func processLinterOutput(output string, fromlinter string) []*models.Issue {
 			Path:        issueData.Pos.Filename,
 			Line:        issueData.Pos.Line,
 			Column:      issueData.Pos.Column,
-			Severity:    models.Severity(issueData.Severity),
+			Severity:    models.ParseSeverityOrDefault(issueData.Severity, models.SeverityWarning),
 			Title:       fmt.Sprintf("%s Issue: %s", fromlinter, issueData.Text),
 			Description: issueData.Text,
 			Suggestion:  "Consider fixing this issue based on the linter's feedback.",
@@ -458,7 +458,7 @@ func processCheckstyleOutput(output string) []*models.Issue {
 				Path:        file.Name,
 				Line:        err.Line,
 				Column:      err.Column,
-				Severity:    models.Severity(strings.ToLower(err.Severity)),
+				Severity:    models.ParseSeverityOrDefault(err.Severity, models.SeverityWarning),
 				Title:       fmt.Sprintf("Checkstyle Issue: %s", filepath.Base(err.Source)),
 				Description: err.Message,
 				Suggestion:  "Fix according to Checkstyle rule.",
@@ -0,0 +1,181 @@
+package symbols
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/keploy/PullPilot/internal/analyzer/diff"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const (
+	maxSnippetLines    = 40
+	maxCallersPerFunc  = 5
+	callerContextLines = 3
+)
+
+// Builder finds code elsewhere in a repository checkout that is relevant to a changed file:
+// definitions of the symbols the change references and callers of the functions it changes.
+// Go is resolved with go/packages; other languages use a ctags-style regex index.
+// Indexes are built lazily on first use and reflect the checkout at that time, so a Builder
+// serves a single run.
+type Builder struct {
+	root string
+
+	mu       sync.Mutex
+	goIndex  *goIndex
+	tagIndex *tagIndex
+	sources  map[string][]string
+}
+
+func NewBuilder(root string) *Builder {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &Builder{root: root}
+}
+
+// RelatedSnippets returns snippets relevant to the changed lines of file, most relevant first.
+func (b *Builder) RelatedSnippets(file *models.File, hunks []*diff.Hunk) []models.CodeSnippet {
+	if b == nil || b.root == "" {
+		return nil
+	}
+
+	changed := changedLines(hunks)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var snippets []models.CodeSnippet
+	switch filepath.Ext(file.Path) {
+	case ".go":
+		if b.goIndex == nil {
+			b.goIndex = newGoIndex()
+		}
+		b.goIndex.loadPackageOf(b.root, filepath.Join(b.root, file.Path))
+		snippets = b.goSnippets(file.Path, changed)
+	case ".py", ".ts", ".tsx", ".js", ".jsx":
+		if b.tagIndex == nil {
+			b.tagIndex = b.buildTagIndex()
+		}
+		snippets = b.tagSnippets(file, changed)
+	default:
+		return nil
+	}
+
+	snippets = dedupeSnippets(snippets)
+	sort.SliceStable(snippets, func(i, j int) bool {
+		if snippets[i].Score != snippets[j].Score {
+			return snippets[i].Score > snippets[j].Score
+		}
+		if snippets[i].Path != snippets[j].Path {
+			return snippets[i].Path < snippets[j].Path
+		}
+		return snippets[i].StartLine < snippets[j].StartLine
+	})
+	log.Printf("Found %d related snippets for %s", len(snippets), file.Path)
+	return snippets
+}
+
+// dedupeSnippets drops snippets whose range is already covered by another snippet of the
+// same file, keeping the higher score.
+func dedupeSnippets(snippets []models.CodeSnippet) []models.CodeSnippet {
+	var result []models.CodeSnippet
+	for _, s := range snippets {
+		merged := false
+		for i, kept := range result {
+			if kept.Path == s.Path && kept.StartLine <= s.StartLine && s.EndLine <= kept.EndLine {
+				if s.Score > kept.Score {
+					result[i].Score = s.Score
+				}
+				merged = true
+				break
+			}
+			if kept.Path == s.Path && s.StartLine <= kept.StartLine && kept.EndLine <= s.EndLine {
+				if kept.Score > s.Score {
+					s.Score = kept.Score
+				}
+				result[i] = s
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func changedLines(hunks []*diff.Hunk) map[int]bool {
+	changed := make(map[int]bool)
+	for _, hunk := range hunks {
+		for _, line := range hunk.Lines {
+			if line.Kind == '+' {
+				changed[line.NewLine] = true
+			}
+		}
+	}
+	return changed
+}
+
+// lines returns the lines of a file relative to the checkout root, caching them for the run.
+func (b *Builder) lines(relPath string) []string {
+	if b.sources == nil {
+		b.sources = make(map[string][]string)
+	}
+	if cached, ok := b.sources[relPath]; ok {
+		return cached
+	}
+
+	data, err := os.ReadFile(filepath.Join(b.root, relPath))
+	if err != nil {
+		b.sources[relPath] = nil
+		return nil
+	}
+	lines := strings.Split(string(data), "\n")
+	b.sources[relPath] = lines
+	return lines
+}
+
+// snippet cuts lines [start, end] (1-based, inclusive) of a file, capped at maxSnippetLines.
+func (b *Builder) snippet(relPath string, start, end int, kind, symbol string, score int) (models.CodeSnippet, bool) {
+	lines := b.lines(relPath)
+	if start < 1 {
+		start = 1
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if end-start+1 > maxSnippetLines {
+		end = start + maxSnippetLines - 1
+	}
+	if start > end {
+		return models.CodeSnippet{}, false
+	}
+
+	return models.CodeSnippet{
+		Path:      relPath,
+		StartLine: start,
+		EndLine:   end,
+		Kind:      kind,
+		Symbol:    symbol,
+		Code:      strings.Join(lines[start-1:end], "\n"),
+		Score:     score,
+	}, true
+}
+
+func (b *Builder) relative(path string) (string, bool) {
+	rel, err := filepath.Rel(b.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
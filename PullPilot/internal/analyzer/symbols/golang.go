@@ -0,0 +1,298 @@
+package symbols
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+type goIndex struct {
+	fset     *token.FileSet
+	packages map[string]*goPackage // keyed by import path
+	files    map[string]*goFile    // keyed by absolute file name
+	dirs     map[string]bool       // Package directories already loaded, or tried
+
+	parsed map[string]*ast.File // Files of other packages, parsed for declaration ranges
+}
+
+type goPackage struct {
+	syntax []*ast.File
+	types  *types.Package
+	info   *types.Info
+}
+
+type goFile struct {
+	pkg  *goPackage
+	file *ast.File
+}
+
+// Only the packages of changed files are type-checked from source. Their dependencies come
+// from export data (NeedTypes without NeedDeps), which go list builds and caches; reading it
+// needs a golang.org/x/tools release at least as new as the Go toolchain.
+const goLoadMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedImports | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+
+func newGoIndex() *goIndex {
+	return &goIndex{
+		fset:     token.NewFileSet(),
+		packages: make(map[string]*goPackage),
+		files:    make(map[string]*goFile),
+		dirs:     make(map[string]bool),
+		parsed:   make(map[string]*ast.File),
+	}
+}
+
+// loadPackageOf type-checks the package of the file at filename, an absolute path below root,
+// so that identifiers referring to other packages resolve to their real objects. A package is
+// loaded once; one with errors is kept with whatever type information could be computed.
+func (index *goIndex) loadPackageOf(root, filename string) {
+	dir := filepath.Dir(filename)
+	if index.dirs[dir] {
+		return
+	}
+	index.dirs[dir] = true
+
+	cfg := &packages.Config{
+		Mode: goLoadMode,
+		Dir:  root,
+		Fset: index.fset,
+	}
+	pkgs, err := packages.Load(cfg, "file="+filename)
+	if err != nil {
+		log.Printf("Warning: Failed to load the Go package of %s: %v", filename, err)
+		return
+	}
+
+	for _, p := range pkgs {
+		if len(p.Errors) > 0 {
+			log.Printf("Warning: %d errors while loading Go package %s; context may be incomplete", len(p.Errors), p.PkgPath)
+		}
+		if p.TypesInfo == nil || len(p.Syntax) == 0 {
+			continue
+		}
+		pkg := &goPackage{syntax: p.Syntax, types: p.Types, info: p.TypesInfo}
+		index.packages[p.PkgPath] = pkg
+		for _, file := range p.Syntax {
+			index.files[index.fset.Position(file.Pos()).Filename] = &goFile{pkg: pkg, file: file}
+		}
+		log.Printf("Loaded Go package %s for cross-file context", p.PkgPath)
+	}
+}
+
+// declLines returns the line range of the top-level declaration at line of a file, including
+// its doc comment. Files of packages that were not loaded are parsed for it.
+func (index *goIndex) declLines(filename string, line int) (int, int, bool) {
+	file := index.parsed[filename]
+	if gf, ok := index.files[filename]; ok {
+		file = gf.file
+	}
+	if file == nil {
+		parsed, err := parser.ParseFile(index.fset, filename, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return 0, 0, false
+		}
+		index.parsed[filename] = parsed
+		file = parsed
+	}
+
+	for _, decl := range file.Decls {
+		start, end := index.fset.Position(declStart(decl)).Line, index.fset.Position(decl.End()).Line
+		if start <= line && line <= end {
+			return start, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// objectKey identifies a package-level object or method by name rather than by identity, so
+// that generic instantiations and their origin compare equal.
+func objectKey(obj types.Object) string {
+	if obj == nil || obj.Pkg() == nil {
+		return ""
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		if sig, ok := fn.Type().(*types.Signature); ok && sig.Recv() != nil {
+			recv := sig.Recv().Type()
+			if ptr, ok := recv.(*types.Pointer); ok {
+				recv = ptr.Elem()
+			}
+			if named, ok := recv.(*types.Named); ok {
+				return fmt.Sprintf("%s.%s.%s", obj.Pkg().Path(), named.Obj().Name(), obj.Name())
+			}
+		}
+	}
+	if obj.Parent() != obj.Pkg().Scope() {
+		if _, isFunc := obj.(*types.Func); !isFunc {
+			return "" // local variables, parameters, fields
+		}
+	}
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
+func (b *Builder) goSnippets(relPath string, changed map[int]bool) []models.CodeSnippet {
+	index := b.goIndex
+	target, ok := index.files[filepath.Join(b.root, relPath)]
+	if !ok || target.pkg.info == nil {
+		return nil
+	}
+	targetName := filepath.Join(b.root, relPath)
+
+	// Definitions of symbols referenced on changed lines.
+	references := make(map[string]int)
+	definitions := make(map[string]types.Object)
+	ast.Inspect(target.file, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok || !changed[index.fset.Position(ident.Pos()).Line] {
+			return true
+		}
+		obj := target.pkg.info.Uses[ident]
+		key := objectKey(obj)
+		if key == "" || index.fset.Position(obj.Pos()).Filename == targetName {
+			return true
+		}
+		references[key]++
+		definitions[key] = obj
+		return true
+	})
+
+	var set []models.CodeSnippet
+	for key, obj := range definitions {
+		if s, ok := b.goDeclSnippet(obj.Pos(), "definition", obj.Name(), references[key]*2); ok {
+			set = append(set, s)
+		}
+	}
+
+	// Callers of functions whose declaration overlaps the changed lines, in the packages
+	// loaded so far: those of the changed files.
+	changedFuncs := make(map[string]string)
+	for _, decl := range target.file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || !overlaps(index.fset, fn, changed) {
+			continue
+		}
+		if key := objectKey(target.pkg.info.Defs[fn.Name]); key != "" {
+			changedFuncs[key] = fn.Name.Name
+		}
+	}
+	if len(changedFuncs) > 0 {
+		callers := make(map[string]int)
+		for _, importPath := range index.sortedPackages() {
+			pkg := index.packages[importPath]
+			if pkg.info == nil {
+				continue
+			}
+			for _, ident := range sortedIdents(index.fset, pkg.info.Uses) {
+				obj := pkg.info.Uses[ident]
+				name, ok := changedFuncs[objectKey(obj)]
+				if !ok || callers[name] >= maxCallersPerFunc {
+					continue
+				}
+				pos := index.fset.Position(ident.Pos())
+				if pos.Filename == targetName {
+					continue
+				}
+				if s, ok := b.goCallerSnippet(pos, name); ok {
+					set = append(set, s)
+					callers[name]++
+				}
+			}
+		}
+	}
+
+	return set
+}
+
+func (index *goIndex) sortedPackages() []string {
+	var paths []string
+	for importPath := range index.packages {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func sortedIdents(fset *token.FileSet, uses map[*ast.Ident]types.Object) []*ast.Ident {
+	idents := make([]*ast.Ident, 0, len(uses))
+	for ident := range uses {
+		idents = append(idents, ident)
+	}
+	sort.Slice(idents, func(i, j int) bool {
+		pi, pj := fset.Position(idents[i].Pos()), fset.Position(idents[j].Pos())
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Offset < pj.Offset
+	})
+	return idents
+}
+
+func overlaps(fset *token.FileSet, node ast.Node, changed map[int]bool) bool {
+	start, end := fset.Position(node.Pos()).Line, fset.Position(node.End()).Line
+	for line := start; line <= end; line++ {
+		if changed[line] {
+			return true
+		}
+	}
+	return false
+}
+
+// goDeclSnippet returns the top-level declaration that contains pos.
+func (b *Builder) goDeclSnippet(pos token.Pos, kind, symbol string, score int) (models.CodeSnippet, bool) {
+	index := b.goIndex
+	position := index.fset.Position(pos)
+	rel, ok := b.relative(position.Filename)
+	if !ok {
+		return models.CodeSnippet{}, false
+	}
+
+	start, end, ok := index.declLines(position.Filename, position.Line)
+	if !ok {
+		start, end = position.Line, position.Line
+	}
+	return b.snippet(rel, start, end, kind, symbol, score)
+}
+
+// goCallerSnippet returns the function enclosing a call site, or a few lines around it.
+func (b *Builder) goCallerSnippet(position token.Position, symbol string) (models.CodeSnippet, bool) {
+	rel, ok := b.relative(position.Filename)
+	if !ok {
+		return models.CodeSnippet{}, false
+	}
+
+	start, end := position.Line-callerContextLines, position.Line+callerContextLines
+	if gf, ok := b.goIndex.files[position.Filename]; ok {
+		for _, decl := range gf.file.Decls {
+			declStartLine := b.goIndex.fset.Position(decl.Pos()).Line
+			declEndLine := b.goIndex.fset.Position(decl.End()).Line
+			if declStartLine <= position.Line && position.Line <= declEndLine && declEndLine-declStartLine < maxSnippetLines {
+				start, end = declStartLine, declEndLine
+				break
+			}
+		}
+	}
+	return b.snippet(rel, start, end, "caller", symbol, 3)
+}
+
+// declStart includes the doc comment in a declaration's range.
+func declStart(decl ast.Decl) token.Pos {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	}
+	return decl.Pos()
+}
@@ -0,0 +1,236 @@
+package symbols
+
+import (
+	"io/fs"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+const maxIndexedFileSize = 1 << 20
+
+var skippedDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true,
+	"venv": true, ".venv": true, "__pycache__": true,
+}
+
+// definitionPatterns capture the defined name in group 1, ctags style.
+var definitionPatterns = map[string][]*regexp.Regexp{
+	"python": {
+		regexp.MustCompile(`^\s*(?:async\s+)?def\s+([A-Za-z_]\w*)\s*\(`),
+		regexp.MustCompile(`^\s*class\s+([A-Za-z_]\w*)`),
+	},
+	"javascript": {
+		regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)\s*[<(]`),
+		regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`),
+		regexp.MustCompile(`^\s*(?:export\s+)?interface\s+([A-Za-z_$][\w$]*)`),
+		regexp.MustCompile(`^\s*(?:export\s+)?type\s+([A-Za-z_$][\w$]*)\s*(?:<[^=]*>)?\s*=`),
+		regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=`),
+	},
+}
+
+var identifierRegex = regexp.MustCompile(`[A-Za-z_$][\w$]*`)
+
+type tag struct {
+	path string
+	line int
+}
+
+type tagIndex struct {
+	definitions map[string]map[string][]tag // language -> name -> definitions
+	files       map[string][]string         // language -> files
+}
+
+func tagLanguage(path string) string {
+	switch filepath.Ext(path) {
+	case ".py":
+		return "python"
+	case ".ts", ".tsx", ".js", ".jsx":
+		return "javascript"
+	}
+	return ""
+}
+
+func (b *Builder) buildTagIndex() *tagIndex {
+	index := &tagIndex{
+		definitions: make(map[string]map[string][]tag),
+		files:       make(map[string][]string),
+	}
+
+	err := filepath.WalkDir(b.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != b.root && skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		language := tagLanguage(path)
+		if language == "" {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxIndexedFileSize {
+			return nil
+		}
+		rel, ok := b.relative(path)
+		if !ok {
+			return nil
+		}
+
+		index.files[language] = append(index.files[language], rel)
+		if index.definitions[language] == nil {
+			index.definitions[language] = make(map[string][]tag)
+		}
+		for i, line := range b.lines(rel) {
+			for _, re := range definitionPatterns[language] {
+				if m := re.FindStringSubmatch(line); m != nil {
+					index.definitions[language][m[1]] = append(index.definitions[language][m[1]], tag{path: rel, line: i + 1})
+					break
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to index %s: %v", b.root, err)
+	}
+	return index
+}
+
+func (b *Builder) tagSnippets(file *models.File, changed map[int]bool) []models.CodeSnippet {
+	language := tagLanguage(file.Path)
+	definitions := b.tagIndex.definitions[language]
+	lines := strings.Split(file.Content, "\n")
+
+	var snippets []models.CodeSnippet
+
+	// Definitions of identifiers used on changed lines.
+	references := make(map[string]int)
+	var order []string
+	defined := make(map[string]bool)
+	for lineNo := range changed {
+		if lineNo < 1 || lineNo > len(lines) {
+			continue
+		}
+		for _, re := range definitionPatterns[language] {
+			if m := re.FindStringSubmatch(lines[lineNo-1]); m != nil {
+				defined[m[1]] = true
+			}
+		}
+		for _, name := range identifierRegex.FindAllString(lines[lineNo-1], -1) {
+			if _, ok := definitions[name]; !ok {
+				continue
+			}
+			if references[name] == 0 {
+				order = append(order, name)
+			}
+			references[name]++
+		}
+	}
+	for _, name := range order {
+		if defined[name] {
+			continue
+		}
+		for _, t := range definitions[name] {
+			if t.path == file.Path {
+				continue
+			}
+			end := b.definitionEnd(t, language)
+			if s, ok := b.snippet(t.path, t.line, end, "definition", name, references[name]*2); ok {
+				snippets = append(snippets, s)
+			}
+		}
+	}
+
+	// Callers of functions defined on changed lines.
+	for name := range defined {
+		callRegex := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\s*\(`)
+		callers := 0
+		for _, path := range b.tagIndex.files[language] {
+			if path == file.Path || callers >= maxCallersPerFunc {
+				continue
+			}
+			for i, line := range b.lines(path) {
+				if callers >= maxCallersPerFunc {
+					break
+				}
+				if !callRegex.MatchString(line) || isDefinitionLine(line, language) {
+					continue
+				}
+				if s, ok := b.snippet(path, i+1-callerContextLines, i+1+callerContextLines, "caller", name, 3); ok {
+					snippets = append(snippets, s)
+					callers++
+				}
+			}
+		}
+	}
+
+	return snippets
+}
+
+func isDefinitionLine(line, language string) bool {
+	for _, re := range definitionPatterns[language] {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// definitionEnd estimates the last line of a definition: by indentation for Python and by
+// brace matching for JavaScript/TypeScript.
+func (b *Builder) definitionEnd(t tag, language string) int {
+	lines := b.lines(t.path)
+	if t.line > len(lines) {
+		return t.line
+	}
+	limit := t.line + maxSnippetLines - 1
+	if limit > len(lines) {
+		limit = len(lines)
+	}
+
+	if language == "python" {
+		indent := indentation(lines[t.line-1])
+		end := t.line
+		for i := t.line + 1; i <= limit; i++ {
+			line := lines[i-1]
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if indentation(line) <= indent {
+				break
+			}
+			end = i
+		}
+		return end
+	}
+
+	depth, opened := 0, false
+	for i := t.line; i <= limit; i++ {
+		for _, r := range lines[i-1] {
+			switch r {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+		if opened && depth <= 0 {
+			return i
+		}
+		if !opened && strings.HasSuffix(strings.TrimSpace(lines[i-1]), ";") {
+			return i
+		}
+	}
+	return limit
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
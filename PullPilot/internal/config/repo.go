@@ -0,0 +1,200 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoConfig is the per-repository configuration read from .pullpilot.yml.
+type RepoConfig struct {
+	Review ReviewConfig `yaml:"review"`
+	Rules  []RuleConfig `yaml:"rules"`
+	Gate   GateConfig   `yaml:"gate"`
+}
+
+// GateConfig is the merge-gate policy, see policy.Gate.
+type GateConfig struct {
+	Rules []GateRule `yaml:"rules"`
+}
+
+// GateRule fails the gate with Message when its CEL expression Deny evaluates to true.
+type GateRule struct {
+	Name    string `yaml:"name"`
+	Deny    string `yaml:"deny"`
+	Message string `yaml:"message"`
+}
+
+type ReviewConfig struct {
+	// PromptTemplate replaces the built-in review prompt. It is a text/template with the
+	// variables .Language, .Path, .Diff, .RelatedCode, .Guidelines, .Personas and .ResponseFormat.
+	PromptTemplate string          `yaml:"prompt_template"`
+	Guidelines     string          `yaml:"guidelines"`
+	Personas       []PersonaConfig `yaml:"personas"`
+}
+
+// PersonaConfig enables a review persona for the files matching Paths (every file when empty).
+// Instructions are required for custom personas and override those of a built-in one.
+type PersonaConfig struct {
+	Name         string   `yaml:"name"`
+	Paths        []string `yaml:"paths"`
+	Instructions string   `yaml:"instructions"`
+}
+
+// RuleConfig is a repository-specific check run by the custom rule engine. An added line is
+// flagged when it matches Pattern (a regular expression) or contains Literal, unless it also
+// matches MustNotMatch. Structural rules instead match code against a semgrep-style pattern
+// such as `$X.Query("..." + $Y)`, parsed and matched as a syntax tree of the file's language, with
+// MustNotMatch applied to the matched code.
+// Languages and Paths restrict the files checked; empty means all.
+type RuleConfig struct {
+	ID           string   `yaml:"id"`
+	Languages    []string `yaml:"languages"`
+	Paths        []string `yaml:"paths"`
+	Pattern      string   `yaml:"pattern"`
+	Literal      string   `yaml:"literal"`
+	Structural   string   `yaml:"structural"`
+	MustNotMatch string   `yaml:"must_not_match"`
+	Severity     string   `yaml:"severity"` // error, warning or info; warning when empty
+	Message      string   `yaml:"message"`
+	Suggestion   string   `yaml:"suggestion"`
+}
+
+// CheckoutDir returns the local checkout of the repository owner/repo, or "" when there is
+// none: <owner>/<repo> below RepoCheckoutDir when it exists, otherwise RepoCheckoutDir itself
+// if it is the checkout of RepoCheckoutRepo. A server receiving webhooks from several
+// repositories must never review one with the files of another.
+func (c *Config) CheckoutDir(owner, repo string) string {
+	if c.RepoCheckoutDir == "" || owner == "" || repo == "" {
+		return ""
+	}
+	name := filepath.Join(owner, repo)
+	if !filepath.IsLocal(name) || strings.ContainsAny(owner+repo, `/\`) {
+		return ""
+	}
+
+	perRepo := filepath.Join(c.RepoCheckoutDir, name)
+	if info, err := os.Stat(perRepo); err == nil && info.IsDir() {
+		return perRepo
+	}
+	if strings.EqualFold(c.RepoCheckoutRepo, owner+"/"+repo) {
+		return c.RepoCheckoutDir
+	}
+	return ""
+}
+
+// RepoConfigFile returns where .pullpilot.yml is read from: PULLPILOT_CONFIG when set,
+// otherwise the root of checkoutDir, or nowhere when both are empty.
+func (c *Config) RepoConfigFile(checkoutDir string) string {
+	if c.RepoConfigPath != "" || checkoutDir == "" {
+		return c.RepoConfigPath
+	}
+	return filepath.Join(checkoutDir, ".pullpilot.yml")
+}
+
+// SecretsAllowlistFile returns the secret scanner allowlist: SECRETS_ALLOWLIST_FILE when set,
+// otherwise .pullpilot-secrets in the root of checkoutDir, or none when both are empty.
+func (c *Config) SecretsAllowlistFile(checkoutDir string) string {
+	if c.SecretsAllowlistPath != "" || checkoutDir == "" {
+		return c.SecretsAllowlistPath
+	}
+	return filepath.Join(checkoutDir, ".pullpilot-secrets")
+}
+
+// LoadRepoConfig reads a .pullpilot.yml file. A missing file is not an error.
+func LoadRepoConfig(path string) (*RepoConfig, error) {
+	repoConfig := &RepoConfig{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return repoConfig, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return ParseRepoConfig(data, path)
+}
+
+// ParseRepoConfig parses the contents of a .pullpilot.yml file; source names it in errors.
+func ParseRepoConfig(data []byte, source string) (*RepoConfig, error) {
+	repoConfig := &RepoConfig{}
+	if err := yaml.Unmarshal(data, repoConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+	return repoConfig, nil
+}
+
+// MatchGlob reports whether a slash-separated path matches a glob pattern. Besides the
+// path.Match syntax, "**" matches any number of directories. A pattern without a slash
+// matches the file name in any directory, like .gitignore.
+func MatchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	name = strings.TrimPrefix(filepath.ToSlash(name), "./")
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+
+	re, err := globRegex(pattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(name)
+}
+
+func globRegex(pattern string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					builder.WriteString("(?:.*/)?")
+				} else {
+					builder.WriteString(".*")
+				}
+			} else {
+				builder.WriteString("[^/]*")
+			}
+		case '?':
+			builder.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				builder.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + class + "]")
+			i += end
+		case '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				builder.WriteString(`\{`)
+				continue
+			}
+			var alternatives []string
+			for _, alt := range strings.Split(pattern[i+1:i+end], ",") {
+				alternatives = append(alternatives, regexp.QuoteMeta(alt))
+			}
+			builder.WriteString("(?:" + strings.Join(alternatives, "|") + ")")
+			i += end
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.Compile(builder.String())
+}
@@ -1,6 +1,7 @@
 package event
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -24,8 +25,57 @@ func NewProcessor(cfg *config.Config) *Processor {
 	}
 }
 
+// reviewableActions are the pull_request webhook actions that change the code under review.
+// Others, such as labeled, closed or edited, are ignored.
+var reviewableActions = map[string]bool{
+	"opened":           true,
+	"synchronize":      true,
+	"reopened":         true,
+	"ready_for_review": true,
+}
+
+// pullRequestEvent is the part of a GitHub pull_request webhook payload used to start a run.
+type pullRequestEvent struct {
+	Action     string `json:"action"`
+	Number     int    `json:"number"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+}
+
 func (p *Processor) ProcessGitHubEvent(eventType string, payload []byte) error {
 
+	// Webhooks from a GitHub App name the repository and installation, so one server can
+	// review pull requests across organisations with the right installation token.
+	var event pullRequestEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", eventType, err)
+	}
+	if !reviewableActions[event.Action] {
+		log.Printf("Ignoring %s event with action %q", eventType, event.Action)
+		return nil
+	}
+	if event.Number > 0 && event.Repository.Name != "" {
+		job := &analyzer.Job{
+			Provider:       "github",
+			RepoOwner:      event.Repository.Owner.Login,
+			RepoName:       event.Repository.Name,
+			PRNumber:       event.Number,
+			InstallationID: event.Installation.ID,
+		}
+		log.Printf("Starting analysis for %s/%s PR #%d (installation %d)", job.RepoOwner, job.RepoName, job.PRNumber, job.InstallationID)
+		if _, err := p.orchestrator.AnalyzeCode(job); err != nil {
+			return fmt.Errorf("failed to analyze code: %w", err)
+		}
+		return nil
+	}
+
 	PullRequest_url := os.Getenv("PULL_REQUEST_URL")
 
 	owner, repoName, err := extractOwnerAndRepo(PullRequest_url)
@@ -1,9 +1,13 @@
 package event
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/keploy/PullPilot/internal/config"
@@ -28,18 +32,26 @@ func (h *WebhookHandler) HandleGitHub(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing signature"})
 		return
 	}
+	if h.cfg.GitHubWebhookSecret == "" {
+		log.Printf("Rejecting GitHub webhook: GITHUB_WEBHOOK_SECRET is not set")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook secret not configured"})
+		return
+	}
 
 	body, err := ioutil.ReadAll(c.Request.Body)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read request body"})
 		return
 	}
+	if !validGitHubSignature(h.cfg.GitHubWebhookSecret, signature, body) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
 
 	eventType := c.GetHeader("X-GitHub-Event")
 
 	if eventType == "pull_request" {
 		go func() {
-			log.Printf("webhook file mein hoon ")
 			if err := h.processor.ProcessGitHubEvent(eventType, body); err != nil {
 				log.Printf("Failed to process GitHub event: %v", err)
 			}
@@ -49,6 +61,22 @@ func (h *WebhookHandler) HandleGitHub(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "processing"})
 }
 
+// SignGitHubPayload returns the X-Hub-Signature-256 header GitHub sends for a payload: the
+// hex HMAC-SHA256 of the body keyed with the webhook secret.
+func SignGitHubPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validGitHubSignature compares a signature header with the payload's in constant time.
+func validGitHubSignature(secret, signature string, body []byte) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(SignGitHubPayload(secret, body)))
+}
+
 func (h *WebhookHandler) HandleGitLab(c *gin.Context) {
 
 	body, err := ioutil.ReadAll(c.Request.Body)
@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/keploy/PullPilot/pkg/models"
 )
@@ -22,9 +23,41 @@ func FormatLinterIssue(issue *models.Issue) *models.ReviewComment {
 		body += "\n\n**Suggestion:** " + issue.Suggestion
 	}
 
-	return &models.ReviewComment{
+	comment := &models.ReviewComment{
 		Path: issue.Path,
 		Line: issue.Line,
 		Body: body,
 	}
+
+	// A suggestion block replaces exactly the lines the comment is anchored to.
+	if fix := issue.Fix; fix != nil {
+		fence := suggestionFence(fix.Replacement)
+		comment.Body += fmt.Sprintf("\n\n%ssuggestion\n%s\n%s", fence, fix.Replacement, fence)
+		comment.Line = fix.EndLine
+		if fix.StartLine != fix.EndLine {
+			comment.StartLine = fix.StartLine
+		}
+	}
+
+	return comment
+}
+
+// suggestionFence returns a backtick fence longer than any backtick run in the code, so
+// that code containing ``` cannot close the block early.
+func suggestionFence(code string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
 }
@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter caps the number of in-flight requests to a service and the rate at which new
+// ones start. A zero limit means unlimited.
+type Limiter struct {
+	slots    chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func NewLimiter(maxConcurrent int, requestsPerSecond float64) *Limiter {
+	l := &Limiter{}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	if requestsPerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return l
+}
+
+// MaxConcurrent is the concurrency cap, or 0 when unlimited.
+func (l *Limiter) MaxConcurrent() int {
+	if l == nil || l.slots == nil {
+		return 0
+	}
+	return cap(l.slots)
+}
+
+// Acquire waits for a free slot and for the rate limit, and returns the function that
+// frees the slot again.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	if l.interval > 0 {
+		l.mu.Lock()
+		now := time.Now()
+		start := l.next
+		if start.Before(now) {
+			start = now
+		}
+		l.next = start.Add(l.interval)
+		l.mu.Unlock()
+
+		if err := sleep(ctx, time.Until(start)); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+var (
+	limitersMu sync.RWMutex
+	limiters   = make(map[string]*Limiter)
+)
+
+// SetLimit configures the limiter of a service. Clients look it up on every request, so it
+// can be set after they are built.
+func SetLimit(service string, maxConcurrent int, requestsPerSecond float64) {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	limiters[service] = NewLimiter(maxConcurrent, requestsPerSecond)
+}
+
+// LimiterFor returns the limiter of a service, or nil when it is not limited.
+func LimiterFor(service string) *Limiter {
+	limitersMu.RLock()
+	defer limitersMu.RUnlock()
+	return limiters[service]
+}
@@ -0,0 +1,278 @@
+// Package httpclient builds the HTTP clients used for every outbound call (LLM providers,
+// GitHub, deps.dev). Requests are rate limited per service and retried with exponential
+// backoff and jitter when the failure is transient.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Policy controls how failed requests are retried.
+type Policy struct {
+	MaxRetries int
+	BaseDelay  time.Duration // Backoff before the first retry, doubled on each further one
+	MaxDelay   time.Duration // Cap on a single wait, including one asked for by Retry-After
+}
+
+var (
+	policyMu      sync.RWMutex
+	defaultPolicy = Policy{MaxRetries: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+)
+
+// SetDefaultPolicy replaces the retry policy of every client built by New or NewIdempotent.
+func SetDefaultPolicy(policy Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	defaultPolicy = policy
+}
+
+func currentPolicy() Policy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return defaultPolicy
+}
+
+// New returns a client for the named service. timeout bounds each attempt rather than the
+// whole call, so retries do not eat into the time of the request being retried.
+func New(service string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &Transport{Service: service, AttemptTimeout: timeout},
+	}
+}
+
+// NewIdempotent returns a client like New for a service whose requests have no side effects
+// whatever their method, such as LLM completions, so that they are all retried like GETs.
+func NewIdempotent(service string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &Transport{Service: service, AttemptTimeout: timeout, Idempotent: true},
+	}
+}
+
+// Transport is an http.RoundTripper that applies the service's Limiter and retries
+// retryable failures according to the default Policy. Requests that are not idempotent,
+// like a POST creating a comment, are only retried when they cannot have been processed.
+type Transport struct {
+	Service        string
+	AttemptTimeout time.Duration
+	Base           http.RoundTripper // The service's transport, see SetCABundle, when nil
+	Idempotent     bool              // Every request is safe to repeat, see NewIdempotent
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return transportFor(t.Service)
+}
+
+// RoundTrip sends req, retrying it as the Policy allows. Retries send a clone of req with a
+// fresh body from GetBody; req itself is never modified.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := currentPolicy()
+	ctx := req.Context()
+	idempotent := t.Idempotent || isIdempotent(req)
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 {
+			var err error
+			if attemptReq, err = cloneRequest(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := t.attempt(attemptReq)
+		wait, retry := retryDelay(resp, err, attempt, policy, idempotent)
+		if !retry || attempt >= policy.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			log.Printf("%s: %s %s returned %s, retrying in %s (attempt %d/%d)",
+				t.Service, req.Method, logURL(req), resp.Status, wait.Round(time.Millisecond), attempt+1, policy.MaxRetries)
+		} else {
+			log.Printf("%s: %s %s failed: %v, retrying in %s (attempt %d/%d)",
+				t.Service, req.Method, logURL(req), err, wait.Round(time.Millisecond), attempt+1, policy.MaxRetries)
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// cloneRequest copies req for another attempt, with its body read again from GetBody.
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("cannot retry %s %s: request body is not replayable", req.Method, logURL(req))
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+	clone.Body = body
+	return clone, nil
+}
+
+// isIdempotent reports whether sending req twice has the same effect as sending it once, by
+// its method or, as net/http decides it, an Idempotency-Key header.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// attempt sends one request through the service's limiter with a per-attempt timeout.
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	release, err := LimiterFor(t.Service).Acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if t.AttemptTimeout <= 0 {
+		return t.base().RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.AttemptTimeout)
+	resp, err := t.base().RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The timeout also covers reading the body, so cancel only once it is closed.
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// retryDelay decides whether a result is worth retrying and how long to wait first.
+// Network errors, 408, 429 and 5xx (except 501) are retried; so are GitHub's rate-limit
+// 403s. A request that is not idempotent may have been processed despite a timeout or a 5xx,
+// so it is only retried when it never reached the server or was rejected unprocessed with
+// 408, 429 or a rate-limit 403. A server-provided delay is honoured unless it exceeds the
+// policy's MaxDelay.
+func retryDelay(resp *http.Response, err error, attempt int, policy Policy, idempotent bool) (time.Duration, bool) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return 0, false
+		}
+		if !idempotent && !notSent(err) {
+			return 0, false
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+			return backoff(attempt, policy), true
+		}
+		return 0, false
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusForbidden && isRateLimited(resp):
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented && idempotent:
+	default:
+		return 0, false
+	}
+
+	if wait, ok := serverDelay(resp); ok {
+		if policy.MaxDelay > 0 && wait > policy.MaxDelay {
+			return 0, false
+		}
+		return wait, true
+	}
+	return backoff(attempt, policy), true
+}
+
+// backoff is exponential with full jitter: a random wait in [0, BaseDelay*2^attempt].
+func backoff(attempt int, policy Policy) time.Duration {
+	ceiling := policy.BaseDelay << uint(attempt)
+	if ceiling <= 0 || (policy.MaxDelay > 0 && ceiling > policy.MaxDelay) {
+		ceiling = policy.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// notSent reports whether a request failed before it was sent: no connection could be made.
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func isRateLimited(resp *http.Response) bool {
+	return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
+
+// serverDelay reads Retry-After (seconds or an HTTP date) or GitHub's X-RateLimit-Reset.
+func serverDelay(resp *http.Response) (time.Duration, bool) {
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return nonNegative(time.Until(at)), true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return nonNegative(time.Until(time.Unix(reset, 0))), true
+		}
+	}
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// logURL leaves out the query string, which carries the API key for some providers.
+func logURL(req *http.Request) string {
+	return req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+var (
+	transportsMu sync.RWMutex
+	transports   = make(map[string]http.RoundTripper)
+)
+
+// SetCABundle makes a service trust the certificates in a PEM bundle in addition to the
+// system roots, for servers behind a private CA such as GitHub Enterprise Server.
+func SetCABundle(service, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return errors.New("CA bundle contains no PEM certificates")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transports[service] = transport
+	return nil
+}
+
+// transportFor returns the base transport of a service, http.DefaultTransport unless
+// SetCABundle configured one.
+func transportFor(service string) http.RoundTripper {
+	transportsMu.RLock()
+	defer transportsMu.RUnlock()
+	if transport, ok := transports[service]; ok {
+		return transport
+	}
+	return http.DefaultTransport
+}
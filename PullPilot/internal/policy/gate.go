@@ -0,0 +1,174 @@
+// Package policy evaluates the merge gate: CEL rules over the issues of a run and the
+// metadata of the pull request that decide whether the review passes.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/keploy/PullPilot/internal/config"
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// PRInfo is the pull request metadata exposed to rules as `pr`.
+type PRInfo struct {
+	Owner        string
+	Repo         string
+	Number       int
+	Title        string
+	Author       string
+	BaseRef      string
+	HeadRef      string
+	Draft        bool
+	Labels       []string
+	ChangedFiles int
+	Additions    int
+	Deletions    int
+}
+
+// Input is what a policy is evaluated over. ChangedLines holds the added lines of each file
+// and sets the `changed` field of issues.
+type Input struct {
+	Issues       []*models.Issue
+	ChangedLines map[string]map[int]string
+	PR           PRInfo
+}
+
+// Gate is a compiled policy. Each rule is a CEL expression that fails the gate when it
+// evaluates to true, for example:
+//
+//	issues.exists(i, i.severity == "error" && i.category == "security" && i.changed)
+//	issues.filter(i, i.severity == "warning").size() > 10
+//	issues.exists(i, i.category == "license-forbidden")
+//
+// Issues have the fields path, line, title, description, severity ("error", "warning" or
+// "info"), source, category and changed. pr has owner, repo, number, title, author,
+// base_ref, head_ref, draft, labels, changed_files, additions and deletions.
+type Gate struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	name    string
+	message string
+	program cel.Program
+}
+
+// Compile checks and compiles the rules of a policy.
+func Compile(rules []config.GateRule) (*Gate, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("issues", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+		cel.Variable("pr", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy environment: %w", err)
+	}
+
+	gate := &Gate{}
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		if rule.Deny == "" {
+			return nil, fmt.Errorf("gate %s: deny expression is required", name)
+		}
+
+		ast, issues := env.Compile(rule.Deny)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("gate %s: %w", name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("gate %s: deny expression must be a bool, got %s", name, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("gate %s: %w", name, err)
+		}
+
+		message := rule.Message
+		if message == "" {
+			message = fmt.Sprintf("%s: %s", name, rule.Deny)
+		}
+		gate.rules = append(gate.rules, compiledRule{name: name, message: message, program: program})
+	}
+	return gate, nil
+}
+
+// Evaluate runs every rule. A rule that fails to evaluate fails the gate, so that a broken
+// policy cannot let a change through unnoticed.
+func (g *Gate) Evaluate(input *Input) *models.GateResult {
+	result := &models.GateResult{Passed: true, Rules: len(g.rules), EvaluatedAt: time.Now()}
+	vars := map[string]interface{}{
+		"issues": issueValues(input),
+		"pr":     prValue(input.PR),
+	}
+
+	for _, rule := range g.rules {
+		denied, err := evalBool(rule.program, vars)
+		if err != nil {
+			result.Passed = false
+			result.Reasons = append(result.Reasons, fmt.Sprintf("%s could not be evaluated: %v", rule.name, err))
+			continue
+		}
+		if denied {
+			result.Passed = false
+			result.Reasons = append(result.Reasons, rule.message)
+		}
+	}
+	return result
+}
+
+func evalBool(program cel.Program, vars map[string]interface{}) (bool, error) {
+	value, _, err := program.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	denied, ok := value.Value().(bool)
+	if !ok {
+		return false, errors.New("result is not a bool")
+	}
+	return denied, nil
+}
+
+func issueValues(input *Input) []interface{} {
+	values := make([]interface{}, 0, len(input.Issues))
+	for _, issue := range input.Issues {
+		_, changed := input.ChangedLines[issue.Path][issue.Line]
+		values = append(values, map[string]interface{}{
+			"path":        issue.Path,
+			"line":        issue.Line,
+			"title":       issue.Title,
+			"description": issue.Description,
+			"severity":    issue.Severity.String(),
+			"source":      issue.Source,
+			"category":    issue.Category,
+			"changed":     changed,
+		})
+	}
+	return values
+}
+
+func prValue(pr PRInfo) map[string]interface{} {
+	labels := make([]interface{}, 0, len(pr.Labels))
+	for _, label := range pr.Labels {
+		labels = append(labels, label)
+	}
+	return map[string]interface{}{
+		"owner":         pr.Owner,
+		"repo":          pr.Repo,
+		"number":        pr.Number,
+		"title":         pr.Title,
+		"author":        pr.Author,
+		"base_ref":      pr.BaseRef,
+		"head_ref":      pr.HeadRef,
+		"draft":         pr.Draft,
+		"labels":        labels,
+		"changed_files": pr.ChangedFiles,
+		"additions":     pr.Additions,
+		"deletions":     pr.Deletions,
+	}
+}
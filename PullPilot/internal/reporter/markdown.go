@@ -101,3 +101,128 @@ func escapeMD(text string) string {
 		"\n", "<br>",
 	).Replace(text)
 }
+
+func GenerateDependencyDiffMarkdown(diff *models.DependencyDiff) string {
+	if diff == nil || diff.Empty() {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n## Supply-Chain Changes\n")
+	builder.WriteString("| Change | Ecosystem | Package | Version | License |\n")
+	builder.WriteString("|--------|-----------|---------|---------|---------|\n")
+
+	for _, dep := range diff.Added {
+		builder.WriteString(fmt.Sprintf("| ➕ Added | %s | `%s` | %s | %s |\n",
+			dep.System, dep.Name, dep.Version, licenseOrDash(dep.License)))
+	}
+	for _, change := range diff.Changed {
+		dep := change.Dependency
+		builder.WriteString(fmt.Sprintf("| 🔄 Changed | %s | `%s` | %s → %s | %s |\n",
+			dep.System, dep.Name, change.OldVersion, dep.Version, licenseOrDash(dep.License)))
+	}
+	for _, dep := range diff.Removed {
+		builder.WriteString(fmt.Sprintf("| ➖ Removed | %s | `%s` | %s | - |\n",
+			dep.System, dep.Name, dep.Version))
+	}
+
+	return builder.String()
+}
+
+func licenseOrDash(license string) string {
+	if license == "" {
+		return "-"
+	}
+	return escapeMD(license)
+}
+
+func GenerateAIStatsMarkdown(stats *models.AIStats) string {
+	if stats == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	if lookups := stats.CacheHits + stats.CacheMisses; lookups > 0 {
+		builder.WriteString(fmt.Sprintf("\n_AI response cache: %d of %d prompts reused (%.0f%% hit ratio)._\n",
+			stats.CacheHits, lookups, stats.CacheHitRatio*100))
+	}
+
+	if stats.GroundingSnapped+stats.GroundingMarked+stats.GroundingDropped > 0 {
+		builder.WriteString(fmt.Sprintf("_AI findings checked against the code: %d moved to the line they describe, %d marked low confidence, %d dropped._\n",
+			stats.GroundingSnapped, stats.GroundingMarked, stats.GroundingDropped))
+	}
+
+	if len(stats.SkippedFiles) > 0 {
+		builder.WriteString("\n## Files Not Reviewed by AI\n")
+		builder.WriteString("| File | Reason |\n")
+		builder.WriteString("|------|--------|\n")
+		for _, skipped := range stats.SkippedFiles {
+			builder.WriteString(fmt.Sprintf("| `%s` | %s |\n", skipped.Path, escapeMD(skipped.Reason)))
+		}
+	}
+
+	return builder.String()
+}
+
+// SummaryMarker identifies PullPilot's sticky summary comment so it can be updated on each push.
+const SummaryMarker = "<!-- pullpilot:summary -->"
+
+// GenerateGateMarkdown reports the merge-gate outcome. A nil result means no policy is set.
+func GenerateGateMarkdown(result *models.GateResult) string {
+	if result == nil {
+		return ""
+	}
+	if result.Passed {
+		return fmt.Sprintf("\n## Quality Gate: ✅ Passed\nAll %d rules passed.\n", result.Rules)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n## Quality Gate: ❌ Failed\n")
+	for _, reason := range result.Reasons {
+		builder.WriteString(fmt.Sprintf("- %s\n", reason))
+	}
+	return builder.String()
+}
+
+func GeneratePRSummaryMarkdown(summary *models.PRSummary) string {
+	var builder strings.Builder
+
+	builder.WriteString(SummaryMarker + "\n")
+	builder.WriteString("## 🧭 PullPilot Summary\n\n")
+	builder.WriteString(summary.Overview + "\n")
+
+	if len(summary.Files) > 0 {
+		builder.WriteString("\n### Walkthrough\n")
+		builder.WriteString("| File | Summary |\n")
+		builder.WriteString("|------|---------|\n")
+		for _, file := range summary.Files {
+			builder.WriteString(fmt.Sprintf("| `%s` | %s |\n", file.Path, escapeMD(file.Summary)))
+		}
+	}
+
+	if len(summary.RiskAreas) > 0 {
+		builder.WriteString("\n### Risk Areas\n")
+		for _, risk := range summary.RiskAreas {
+			builder.WriteString("- " + risk + "\n")
+		}
+	}
+
+	if len(summary.ReviewOrder) > 0 {
+		builder.WriteString("\n### Suggested Review Order\n")
+		for i, path := range summary.ReviewOrder {
+			builder.WriteString(fmt.Sprintf("%d. `%s`\n", i+1, path))
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("\n<sub>Updated %s</sub>\n", time.Now().Format(time.RFC1123)))
+	return builder.String()
+}
+
+// GenerateUsageFooter renders the LLM usage of a run as a one-line footer.
+func GenerateUsageFooter(usage models.UsageTotals) string {
+	if usage.Calls == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n---\n<sub>%d LLM call(s) · %d prompt + %d completion tokens · ~$%.4f · %.1fs</sub>\n",
+		usage.Calls, usage.PromptTokens, usage.CompletionTokens, usage.CostUSD, float64(usage.LatencyMS)/1000)
+}
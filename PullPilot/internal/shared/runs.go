@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// Run is the record of a single PR review, kept for the results API.
+type Run struct {
+	ID          string             `json:"id"`
+	RepoOwner   string             `json:"repo_owner"`
+	RepoName    string             `json:"repo_name"`
+	PRNumber    int                `json:"pr_number"`
+	StartedAt   time.Time          `json:"started_at"`
+	CompletedAt time.Time          `json:"completed_at"`
+	Issues      []*models.Issue    `json:"issues"`
+	AIStats     *models.AIStats    `json:"ai_stats,omitempty"`
+	Gate        *models.GateResult `json:"gate,omitempty"`
+
+	CycloneDXSBOM []byte                 `json:"-"` // Served by /api/results/:id/sbom
+	SPDXSBOM      []byte                 `json:"-"`
+	SBOMDiff      *models.DependencyDiff `json:"sbom_diff,omitempty"`
+}
+
+var (
+	runsMu   sync.RWMutex
+	runs     = make(map[string]*Run)
+	runOrder []string // IDs in the order the runs were saved
+
+	maxRuns = 200
+	runTTL  = 24 * time.Hour
+)
+
+// SetRunRetention bounds the runs kept in memory: at most max runs (0 for no limit), each for
+// at most ttl after it completed (0 for no expiry). The oldest runs are dropped first.
+func SetRunRetention(max int, ttl time.Duration) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	maxRuns, runTTL = max, ttl
+	evictRuns(time.Now())
+}
+
+func SaveRun(run *Run) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	if _, ok := runs[run.ID]; !ok {
+		runOrder = append(runOrder, run.ID)
+	}
+	runs[run.ID] = run
+	evictRuns(time.Now())
+}
+
+func GetRun(id string) (*Run, bool) {
+	runsMu.RLock()
+	defer runsMu.RUnlock()
+	run, ok := runs[id]
+	if ok && expired(run, time.Now()) {
+		return nil, false
+	}
+	return run, ok
+}
+
+// evictRuns drops expired runs and the oldest ones over the limit. runsMu must be held.
+func evictRuns(now time.Time) {
+	drop := 0
+	for drop < len(runOrder) {
+		run := runs[runOrder[drop]]
+		if !expired(run, now) && (maxRuns <= 0 || len(runOrder)-drop <= maxRuns) {
+			break
+		}
+		delete(runs, runOrder[drop])
+		drop++
+	}
+	runOrder = append(runOrder[:0], runOrder[drop:]...)
+}
+
+func expired(run *Run, now time.Time) bool {
+	return runTTL > 0 && now.Sub(run.CompletedAt) > runTTL
+}
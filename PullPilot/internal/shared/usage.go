@@ -0,0 +1,152 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// UsageReport is the LLM usage roll-up served by the usage API.
+type UsageReport struct {
+	ByRepo       map[string]models.UsageTotals `json:"by_repo"`
+	ByDay        map[string]models.UsageTotals `json:"by_day"` // UTC days, "2006-01-02"
+	MonthToDate  models.UsageTotals            `json:"month_to_date"`
+	MonthlyLimit float64                       `json:"monthly_budget_usd,omitempty"`
+}
+
+// usageRecord is what the usage store keeps per repo and day. Individual calls stay on the run.
+type usageRecord struct {
+	Repo   string             `json:"repo"`
+	Day    string             `json:"day"`
+	Totals models.UsageTotals `json:"totals"`
+}
+
+var (
+	usageMu   sync.Mutex
+	usage     = make(map[string]*usageRecord) // keyed by repo + "|" + day
+	usagePath string
+)
+
+// LoadUsage makes the usage store persistent at path, reading what is already there, so that
+// the monthly budget survives restarts. Without it usage is kept in memory only.
+func LoadUsage(path string) error {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	usagePath = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read usage store: %w", err)
+	}
+
+	var records []*usageRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse usage store: %w", err)
+	}
+	for _, record := range records {
+		usage[record.Repo+"|"+record.Day] = record
+	}
+	return nil
+}
+
+// RecordUsage adds the LLM calls of a run to the per-repo and per-day roll-ups.
+func RecordUsage(repo string, calls []models.LLMCall) {
+	if len(calls) == 0 {
+		return
+	}
+
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	for _, call := range calls {
+		day := call.At.UTC().Format("2006-01-02")
+		record, ok := usage[repo+"|"+day]
+		if !ok {
+			record = &usageRecord{Repo: repo, Day: day}
+			usage[repo+"|"+day] = record
+		}
+		record.Totals.Add(call)
+	}
+
+	if err := saveUsage(); err != nil {
+		fmt.Println("Error saving usage store:", err)
+	}
+}
+
+func saveUsage() error {
+	if usagePath == "" {
+		return nil
+	}
+
+	records := make([]*usageRecord, 0, len(usage))
+	for _, record := range usage {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Day != records[j].Day {
+			return records[i].Day < records[j].Day
+		}
+		return records[i].Repo < records[j].Repo
+	})
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := usagePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, usagePath)
+}
+
+// MonthToDateCost is the LLM spend of the current UTC calendar month across all repos.
+func MonthToDateCost(now time.Time) float64 {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	return monthToDate(now).CostUSD
+}
+
+func monthToDate(now time.Time) models.UsageTotals {
+	month := now.UTC().Format("2006-01")
+	var totals models.UsageTotals
+	for _, record := range usage {
+		if record.Day[:7] == month {
+			totals.Merge(record.Totals)
+		}
+	}
+	return totals
+}
+
+// GetUsage rolls usage up per repo and per day. repo filters to one "owner/name" when set.
+func GetUsage(repo string, now time.Time) *UsageReport {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	report := &UsageReport{
+		ByRepo:      make(map[string]models.UsageTotals),
+		ByDay:       make(map[string]models.UsageTotals),
+		MonthToDate: monthToDate(now),
+	}
+	for _, record := range usage {
+		if repo != "" && record.Repo != repo {
+			continue
+		}
+		byRepo := report.ByRepo[record.Repo]
+		byRepo.Merge(record.Totals)
+		report.ByRepo[record.Repo] = byRepo
+
+		byDay := report.ByDay[record.Day]
+		byDay.Merge(record.Totals)
+		report.ByDay[record.Day] = byDay
+	}
+	return report
+}
@@ -0,0 +1,210 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/keploy/PullPilot/internal/httpclient"
+)
+
+// Installation tokens live for an hour; they are renewed once less than this is left so that
+// a token never expires in the middle of a run.
+const tokenRefreshMargin = 5 * time.Minute
+
+// AppAuth authenticates as a GitHub App. It signs JWTs with the App's private key and
+// exchanges them for installation tokens, which are cached per installation and refreshed
+// before they expire.
+type AppAuth struct {
+	appID      int64
+	key        *rsa.PrivateKey
+	baseURL    string
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	tokens map[int64]*cachedToken
+	repos  map[string]int64 // "owner/repo" -> installation ID
+	slug   string
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// cachedToken is the token of one installation. Its mutex is held while the token is renewed,
+// so concurrent runs of an installation share one exchange without holding up the others.
+type cachedToken struct {
+	mu    sync.Mutex
+	token installationToken
+}
+
+// NewAppAuth parses the App's PEM private key, in PKCS#1 or PKCS#8 form.
+func NewAppAuth(appID int64, privateKeyPEM []byte) (*AppAuth, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("GitHub App private key is not PEM encoded")
+	}
+
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = parsed
+	} else {
+		pkcs8, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+		}
+		rsaKey, ok := pkcs8.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("GitHub App private key is not an RSA key")
+		}
+		key = rsaKey
+	}
+
+	return &AppAuth{
+		appID:      appID,
+		key:        key,
+		baseURL:    currentEndpoints().APIURL,
+		httpClient: httpclient.New("github", 30*time.Second),
+		tokens:     make(map[int64]*cachedToken),
+		repos:      make(map[string]int64),
+	}, nil
+}
+
+// JWT returns an RS256 token identifying the App, valid for nine minutes. It is backdated
+// by a minute to allow for clock drift, as GitHub recommends.
+func (a *AppAuth) JWT() (string, error) {
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(a.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// InstallationToken returns a valid token for an installation, requesting a new one when
+// there is none cached or the cached one is about to expire.
+func (a *AppAuth) InstallationToken(ctx context.Context, installationID int64) (string, error) {
+	a.mu.Lock()
+	cached, ok := a.tokens[installationID]
+	if !ok {
+		cached = &cachedToken{}
+		a.tokens[installationID] = cached
+	}
+	a.mu.Unlock()
+
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+	if time.Until(cached.token.ExpiresAt) > tokenRefreshMargin {
+		return cached.token.Token, nil
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", a.baseURL, installationID)
+	var token installationToken
+	if err := a.appRequest(ctx, http.MethodPost, url, &token); err != nil {
+		return "", fmt.Errorf("failed to create installation token: %w", err)
+	}
+	cached.token = token
+	log.Printf("Refreshed GitHub App token for installation %d, expires at %s", installationID, token.ExpiresAt.Format(time.RFC3339))
+	return token.Token, nil
+}
+
+// Slug returns the App's URL-friendly name; its bot user is "<slug>[bot]".
+func (a *AppAuth) Slug(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	slug := a.slug
+	a.mu.Unlock()
+	if slug != "" {
+		return slug, nil
+	}
+
+	var app struct {
+		Slug string `json:"slug"`
+	}
+	if err := a.appRequest(ctx, http.MethodGet, a.baseURL+"/app", &app); err != nil {
+		return "", fmt.Errorf("failed to get the GitHub App: %w", err)
+	}
+	a.mu.Lock()
+	a.slug = app.Slug
+	a.mu.Unlock()
+	return app.Slug, nil
+}
+
+// InstallationForRepo looks up the installation of the App on a repository, for runs that
+// do not come with one, such as those started from an Action.
+func (a *AppAuth) InstallationForRepo(ctx context.Context, owner, repo string) (int64, error) {
+	a.mu.Lock()
+	id, ok := a.repos[owner+"/"+repo]
+	a.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/installation", a.baseURL, owner, repo)
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	if err := a.appRequest(ctx, http.MethodGet, url, &installation); err != nil {
+		return 0, fmt.Errorf("failed to find the App installation of %s/%s: %w", owner, repo, err)
+	}
+	a.mu.Lock()
+	a.repos[owner+"/"+repo] = installation.ID
+	a.mu.Unlock()
+	return installation.ID, nil
+}
+
+// appRequest sends a request authenticated as the App itself.
+func (a *AppAuth) appRequest(ctx context.Context, method, url string, out interface{}) error {
+	jwt, err := a.JWT()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("GitHub API error: %s, response: %s", resp.Status, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
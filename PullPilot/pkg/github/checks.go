@@ -0,0 +1,164 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+// Limits of the checks API.
+const (
+	maxAnnotationsPerRequest = 50
+	maxCheckSummary          = 65535
+)
+
+// CheckAnnotation marks an issue on the lines of a file in a check run.
+type CheckAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"` // notice, warning or failure
+	Title           string `json:"title,omitempty"`
+	Message         string `json:"message"`
+}
+
+type checkOutput struct {
+	Title       string            `json:"title"`
+	Summary     string            `json:"summary"`
+	Annotations []CheckAnnotation `json:"annotations,omitempty"`
+}
+
+type checkRun struct {
+	ID int64 `json:"id"`
+}
+
+// CreateCheckRun creates a queued check run on a commit and returns its ID.
+func (c *Client) CreateCheckRun(ctx context.Context, owner, repo, name, headSHA string) (int64, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/check-runs", c.baseURL, owner, repo)
+	payload := map[string]interface{}{
+		"name":     name,
+		"head_sha": headSHA,
+		"status":   "queued",
+	}
+	var created checkRun
+	if err := c.doJSON(ctx, http.MethodPost, url, payload, &created); err != nil {
+		return 0, fmt.Errorf("failed to create check run: %w", err)
+	}
+	return created.ID, nil
+}
+
+// StartCheckRun moves a check run to in_progress.
+func (c *Client) StartCheckRun(ctx context.Context, owner, repo string, id int64) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/check-runs/%d", c.baseURL, owner, repo, id)
+	payload := map[string]interface{}{
+		"status":     "in_progress",
+		"started_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := c.doJSON(ctx, http.MethodPatch, url, payload, nil); err != nil {
+		return fmt.Errorf("failed to start check run %d: %w", id, err)
+	}
+	return nil
+}
+
+// CompleteCheckRun publishes the annotations of a check run in batches of 50, the most the API
+// accepts per request, then completes it with conclusion (success, failure, neutral, ...).
+// summary is Markdown and is truncated to the API's limit. When a batch fails the run is still
+// completed, without the remaining annotations, so that it does not stay in progress.
+func (c *Client) CompleteCheckRun(ctx context.Context, owner, repo string, id int64, conclusion, title, summary string, annotations []CheckAnnotation) error {
+	summary = truncate(summary, maxCheckSummary, truncatedNote)
+	url := fmt.Sprintf("%s/repos/%s/%s/check-runs/%d", c.baseURL, owner, repo, id)
+
+	for start := 0; ; start += maxAnnotationsPerRequest {
+		end := start + maxAnnotationsPerRequest
+		if end > len(annotations) {
+			end = len(annotations)
+		}
+		payload := map[string]interface{}{
+			"output": checkOutput{Title: title, Summary: summary, Annotations: annotations[start:end]},
+		}
+		// Annotations accumulate across updates; the last batch completes the run.
+		last := end == len(annotations)
+		if last {
+			completeCheckPayload(payload, conclusion)
+		}
+		if err := c.doJSON(ctx, http.MethodPatch, url, payload, nil); err != nil {
+			err = fmt.Errorf("failed to update check run %d: %w", id, err)
+			if last {
+				return err
+			}
+			payload := map[string]interface{}{"output": checkOutput{Title: title, Summary: summary}}
+			completeCheckPayload(payload, conclusion)
+			if completeErr := c.doJSON(ctx, http.MethodPatch, url, payload, nil); completeErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to complete check run %d: %w", id, completeErr))
+			}
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+func completeCheckPayload(payload map[string]interface{}, conclusion string) {
+	payload["status"] = "completed"
+	payload["conclusion"] = conclusion
+	payload["completed_at"] = time.Now().UTC().Format(time.RFC3339)
+}
+
+const truncatedNote = "\n\n_Report truncated._"
+
+// truncate shortens s to at most limit bytes, ending with note, without splitting a UTF-8
+// character.
+func truncate(s string, limit int, note string) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := limit - len(note)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + note
+}
+
+// IssueAnnotations converts issues to check annotations. Issues without a line, such as
+// dependency findings, are attached to the first line of their file.
+func IssueAnnotations(issues []*models.Issue) []CheckAnnotation {
+	annotations := make([]CheckAnnotation, 0, len(issues))
+	for _, issue := range issues {
+		level := "notice"
+		switch issue.Severity {
+		case models.SeverityError:
+			level = "failure"
+		case models.SeverityWarning:
+			level = "warning"
+		}
+
+		line := issue.Line
+		if line < 1 {
+			line = 1
+		}
+		message := issue.Description
+		if issue.Suggestion != "" {
+			message += "\n\nSuggestion: " + issue.Suggestion
+		}
+		if message == "" {
+			message = issue.Title
+		}
+		title := truncate(issue.Title, 255, "...")
+
+		annotations = append(annotations, CheckAnnotation{
+			Path:            issue.Path,
+			StartLine:       line,
+			EndLine:         line,
+			AnnotationLevel: level,
+			Title:           title,
+			Message:         message,
+		})
+	}
+	return annotations
+}
@@ -5,20 +5,24 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
-	"os"
-
+	"github.com/keploy/PullPilot/internal/httpclient"
 	"github.com/keploy/PullPilot/internal/shared"
 	"github.com/keploy/PullPilot/pkg/models"
 )
 
 var pullnumber int
 
+// ErrNotFound is wrapped by the errors of requests GitHub answers with 404 Not Found.
+var ErrNotFound = errors.New("not found")
+
 func PullRequestNumber(currentpullnumber int) int {
 	pullnumber = currentpullnumber
 	return pullnumber
@@ -30,16 +34,94 @@ type Client struct {
 	token      string
 	httpClient *http.Client
 	baseURL    string
+	endpoints  Endpoints
+
+	// With app set, requests use installation tokens of installationID instead of token.
+	app            *AppAuth
+	installationID int64
+
+	loginMu       sync.Mutex
+	cachedLogin   string // See login
+	loginResolved bool
 }
 
+// NewClient returns a client for the GitHub instance set with SetEndpoints, github.com by
+// default.
 func NewClient(token string) *Client {
+	endpoints := currentEndpoints()
 	return &Client{
-		token: token,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseURL: "https://api.github.com",
+		token:      token,
+		httpClient: httpclient.New("github", 30*time.Second),
+		baseURL:    endpoints.APIURL,
+		endpoints:  endpoints,
+	}
+}
+
+// NewAppClient returns a client that acts as a GitHub App installation, so that comments,
+// reviews and checks come from the App's bot account.
+func NewAppClient(app *AppAuth, installationID int64) *Client {
+	client := NewClient("")
+	client.app = app
+	client.installationID = installationID
+	return client
+}
+
+// authorize sets the Authorization header of a GitHub API request.
+func (c *Client) authorize(req *http.Request) error {
+	token := c.token
+	if c.app != nil {
+		var err error
+		if token, err = c.app.InstallationToken(req.Context(), c.installationID); err != nil {
+			return err
+		}
 	}
+	req.Header.Set("Authorization", "token "+token)
+	return nil
+}
+
+// APIURL returns the base URL of the REST API the client talks to.
+func (c *Client) APIURL() string {
+	return c.baseURL
+}
+
+// Get sends an authenticated GET request. pathOrURL is either a path relative to the API
+// URL or an absolute URL; credentials are only attached to URLs on the configured GitHub
+// hosts. Error statuses are returned as errors, otherwise the caller closes the body.
+func (c *Client) Get(ctx context.Context, pathOrURL, accept string) (*http.Response, error) {
+	url := pathOrURL
+	if strings.HasPrefix(url, "/") {
+		url = c.baseURL + url
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	if c.endpoints.trusts(url) {
+		if err := c.authorize(req); err != nil {
+			return nil, err
+		}
+	}
+	if accept == "" {
+		accept = "application/vnd.github.v3+json"
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request for %s: %w", url, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GitHub API error for %s: %w", url, ErrNotFound)
+	}
+	if resp.StatusCode >= 400 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("GitHub API error for %s: %s, response: %s", url, resp.Status, string(body))
+	}
+	return resp, nil
 }
 
 type File struct {
@@ -56,10 +138,11 @@ func (c *Client) GetChangedFiles(ctx context.Context, owner, repo string, pullNu
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "token "+c.token)
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
-	fmt.Printf("Base64 encoded token: %s\n", base64.StdEncoding.EncodeToString([]byte(c.token)))
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -72,9 +155,11 @@ func (c *Client) GetChangedFiles(ctx context.Context, owner, repo string, pullNu
 	}
 
 	var prFiles []struct {
-		Filename string `json:"filename"`
-		Status   string `json:"status"`
-		RawURL   string `json:"raw_url"`
+		Filename    string `json:"filename"`
+		Status      string `json:"status"`
+		RawURL      string `json:"raw_url"`
+		ContentsURL string `json:"contents_url"`
+		Patch       string `json:"patch"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&prFiles); err != nil {
@@ -87,7 +172,7 @@ func (c *Client) GetChangedFiles(ctx context.Context, owner, repo string, pullNu
 			continue // Skip deleted files
 		}
 
-		content, err := fetchRawContent(ctx, prFile.RawURL)
+		content, err := c.fetchRawContent(ctx, prFile.ContentsURL, prFile.RawURL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch content for %s: %w", prFile.Filename, err)
 		}
@@ -95,29 +180,29 @@ func (c *Client) GetChangedFiles(ctx context.Context, owner, repo string, pullNu
 		files = append(files, &models.File{
 			Path:    prFile.Filename,
 			Content: content,
+			Status:  prFile.Status,
+			Patch:   prFile.Patch,
 		})
 	}
 
 	return files, nil
 }
 
-func fetchRawContent(ctx context.Context, rawURL string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request for raw content: %w", err)
+// fetchRawContent downloads a file at the PR head through the contents API, which works on
+// private repositories and GitHub Enterprise Server alike. The raw URL is only a fallback
+// for files listed without a contents URL.
+func (c *Client) fetchRawContent(ctx context.Context, contentsURL, rawURL string) (string, error) {
+	url, accept := contentsURL, "application/vnd.github.raw"
+	if url == "" {
+		url, accept = rawURL, ""
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.Get(ctx, url, accept)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch raw content: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("GitHub raw file error: %s, response: %s", resp.Status, string(body))
-	}
-
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return "", fmt.Errorf("failed to read raw content: %w", err)
@@ -179,32 +264,6 @@ func fetchRawContent(ctx context.Context, rawURL string) (string, error) {
 // 	return nil
 // }
 
-func (c *Client) CreateReview(ctx context.Context, owner, repo string, pullnumber int, comments []*models.ReviewComment) error {
-	// Format the comment
-	var markdownComment string
-	markdownComment += "### 📝 Automated Review Comments\n\n"
-	markdownComment += "Thank you for raising this pull request. Below are the review comments:\n\n"
-
-	for _, comment := range comments {
-		markdownComment += fmt.Sprintf(
-			"- **File:** %s\n  - **Position:** %d\n  - **Comment:** %s\n\n",
-			comment.Path, comment.Line, comment.Body,
-		)
-	}
-
-	// Generate filename using current timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	filename := fmt.Sprintf("Before_review_%s.md", timestamp)
-
-	// Write to file
-	err := os.WriteFile(filename, []byte(markdownComment), 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write review to file: %w", err)
-	}
-
-	log.Printf("*********************************************************************************************Review comments saved to file: %s\n", filename)
-	return nil
-}
 func base64Decode(content string) ([]byte, error) {
 	decoded, err := base64.StdEncoding.DecodeString(content)
 	if err != nil {
@@ -0,0 +1,162 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type issueComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+	User struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"user"`
+}
+
+// UpsertStickyComment keeps a single PR comment identified by marker up to date: the first
+// comment of the client's own account containing marker is edited in place, otherwise a new
+// comment is created. Comments of other users are never taken over, even if they quote marker.
+func (c *Client) UpsertStickyComment(ctx context.Context, owner, repo string, pullNumber int, marker, body string) error {
+	if !strings.Contains(body, marker) {
+		body = marker + "\n" + body
+	}
+
+	existing, err := c.findComment(ctx, owner, repo, pullNumber, marker)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]string{"body": body}
+	if existing != nil {
+		url := fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d", c.baseURL, owner, repo, existing.ID)
+		if err := c.doJSON(ctx, http.MethodPatch, url, payload, nil); err != nil {
+			return fmt.Errorf("failed to update comment %d: %w", existing.ID, err)
+		}
+		log.Printf("Updated sticky comment %d on %s/%s#%d", existing.ID, owner, repo, pullNumber)
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", c.baseURL, owner, repo, pullNumber)
+	var created issueComment
+	if err := c.doJSON(ctx, http.MethodPost, url, payload, &created); err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	log.Printf("Created sticky comment %d on %s/%s#%d", created.ID, owner, repo, pullNumber)
+	return nil
+}
+
+func (c *Client) findComment(ctx context.Context, owner, repo string, pullNumber int, marker string) (*issueComment, error) {
+	login, err := c.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments?per_page=100&page=%d", c.baseURL, owner, repo, pullNumber, page)
+		var comments []issueComment
+		if err := c.doJSON(ctx, http.MethodGet, url, nil, &comments); err != nil {
+			return nil, fmt.Errorf("failed to list comments: %w", err)
+		}
+		for i := range comments {
+			if postedBy(&comments[i], login) && strings.Contains(comments[i].Body, marker) {
+				return &comments[i], nil
+			}
+		}
+		if len(comments) < 100 {
+			return nil, nil
+		}
+	}
+}
+
+// postedBy reports whether a comment is by login, or by a bot account when login is unknown.
+func postedBy(comment *issueComment, login string) bool {
+	if login == "" {
+		return comment.User.Type == "Bot"
+	}
+	return strings.EqualFold(comment.User.Login, login)
+}
+
+// login returns the account the client posts as: the App's bot user, or the owner of the
+// token. It is "" for installation and workflow tokens, which cannot look themselves up and
+// post as a bot account.
+func (c *Client) login(ctx context.Context) (string, error) {
+	c.loginMu.Lock()
+	login, resolved := c.cachedLogin, c.loginResolved
+	c.loginMu.Unlock()
+	if resolved {
+		return login, nil
+	}
+
+	if c.app != nil {
+		slug, err := c.app.Slug(ctx)
+		if err != nil {
+			return "", err
+		}
+		login = slug + "[bot]"
+	} else {
+		var user struct {
+			Login string `json:"login"`
+		}
+		if err := c.doJSON(ctx, http.MethodGet, c.baseURL+"/user", nil, &user); err != nil {
+			log.Printf("Token user unavailable, matching comments of bot accounts: %v", err)
+		}
+		login = user.Login
+	}
+
+	c.loginMu.Lock()
+	c.cachedLogin, c.loginResolved = login, true
+	c.loginMu.Unlock()
+	return login, nil
+}
+
+// doJSON sends an authenticated GitHub API request with an optional JSON body and decodes
+// the JSON response into out when it is non-nil.
+func (c *Client) doJSON(ctx context.Context, method, url string, in, out interface{}) error {
+	var body *bytes.Buffer
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewBuffer(data)
+	} else {
+		body = &bytes.Buffer{}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("GitHub API error: %s, response: %s", resp.Status, string(respBody))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package github
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Endpoints are the base URLs of a GitHub instance.
+type Endpoints struct {
+	APIURL string // REST API, e.g. https://ghes.example.com/api/v3
+	RawURL string // Raw file host, e.g. https://ghes.example.com/raw
+}
+
+var (
+	endpointsMu      sync.RWMutex
+	defaultEndpoints = Endpoints{
+		APIURL: "https://api.github.com",
+		RawURL: "https://raw.githubusercontent.com",
+	}
+)
+
+// SetEndpoints points clients created afterwards at another GitHub instance. For GitHub
+// Enterprise Server only the API URL is needed: a raw URL left empty is derived from an API
+// URL ending in /api/v3.
+func SetEndpoints(endpoints Endpoints) {
+	endpoints.APIURL = strings.TrimSuffix(endpoints.APIURL, "/")
+	if endpoints.APIURL == "" {
+		endpoints.APIURL = "https://api.github.com"
+	}
+	if host := strings.TrimSuffix(endpoints.APIURL, "/api/v3"); host != endpoints.APIURL && endpoints.RawURL == "" {
+		endpoints.RawURL = host + "/raw"
+	}
+	if endpoints.RawURL == "" {
+		endpoints.RawURL = "https://raw.githubusercontent.com"
+	}
+
+	endpointsMu.Lock()
+	defer endpointsMu.Unlock()
+	defaultEndpoints = Endpoints{
+		APIURL: endpoints.APIURL,
+		RawURL: strings.TrimSuffix(endpoints.RawURL, "/"),
+	}
+}
+
+func currentEndpoints() Endpoints {
+	endpointsMu.RLock()
+	defer endpointsMu.RUnlock()
+	return defaultEndpoints
+}
+
+// trusts reports whether credentials may be sent to rawURL: only to the hosts of the
+// configured endpoints, never to a host a response pointed at.
+func (e Endpoints) trusts(rawURL string) bool {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	for _, endpoint := range []string{e.APIURL, e.RawURL} {
+		if parsed, err := url.Parse(endpoint); err == nil && strings.EqualFold(parsed.Host, target.Host) && parsed.Scheme == target.Scheme {
+			return true
+		}
+	}
+	return false
+}
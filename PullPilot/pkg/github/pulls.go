@@ -0,0 +1,74 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// PullRequest is the subset of the GitHub pull request object PullPilot uses.
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Draft  bool   `json:"draft"`
+	User   struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Head struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"base"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	ChangedFiles int `json:"changed_files"`
+	Additions    int `json:"additions"`
+	Deletions    int `json:"deletions"`
+}
+
+func (c *Client) GetPullRequest(ctx context.Context, owner, repo string, pullNumber int) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, owner, repo, pullNumber)
+	var pr PullRequest
+	if err := c.doJSON(ctx, http.MethodGet, url, nil, &pr); err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+	return &pr, nil
+}
+
+// GetFileContent returns a file of the repository at ref, a branch, tag or commit SHA. The
+// error wraps ErrNotFound when the file does not exist there.
+func (c *Client) GetFileContent(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	resp, err := c.Get(ctx, fmt.Sprintf("/repos/%s/%s/contents/%s?ref=%s", owner, repo, path, url.QueryEscape(ref)), "application/vnd.github.raw")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s at %s: %w", path, ref, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %s: %w", path, ref, err)
+	}
+	return data, nil
+}
+
+// CreateCommitStatus sets the status named statusContext on a commit. state is one of
+// "pending", "success", "failure" or "error"; GitHub truncates description at 140 characters.
+func (c *Client) CreateCommitStatus(ctx context.Context, owner, repo, sha, state, statusContext, description string) error {
+	description = truncate(description, 140, "...")
+	url := fmt.Sprintf("%s/repos/%s/%s/statuses/%s", c.baseURL, owner, repo, sha)
+	payload := map[string]string{
+		"state":       state,
+		"context":     statusContext,
+		"description": description,
+	}
+	if err := c.doJSON(ctx, http.MethodPost, url, payload, nil); err != nil {
+		return fmt.Errorf("failed to set commit status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/keploy/PullPilot/pkg/models"
+)
+
+type reviewComment struct {
+	Path      string `json:"path"`
+	Body      string `json:"body"`
+	Line      int    `json:"line"`
+	Side      string `json:"side"`
+	StartLine int    `json:"start_line,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+}
+
+// CreateReview posts a pull request review with inline comments on the new side of the diff.
+// Comments with a StartLine span StartLine..Line, which is what a suggestion block replaces.
+// Every comment must be on a line of the diff, otherwise GitHub rejects the whole review.
+// commitID pins the comments to the commit that was analyzed; empty means the PR head.
+func (c *Client) CreateReview(ctx context.Context, owner, repo string, pullNumber int, commitID, body string, comments []*models.ReviewComment) error {
+	inline := make([]reviewComment, 0, len(comments))
+	for _, comment := range comments {
+		rc := reviewComment{Path: comment.Path, Body: comment.Body, Line: comment.Line, Side: "RIGHT"}
+		if comment.StartLine > 0 && comment.StartLine < comment.Line {
+			rc.StartLine = comment.StartLine
+			rc.StartSide = "RIGHT"
+		}
+		inline = append(inline, rc)
+	}
+
+	payload := map[string]interface{}{
+		"event":    "COMMENT",
+		"body":     body,
+		"comments": inline,
+	}
+	if commitID != "" {
+		payload["commit_id"] = commitID
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews", c.baseURL, owner, repo, pullNumber)
+	if err := c.doJSON(ctx, http.MethodPost, url, payload, nil); err != nil {
+		return fmt.Errorf("failed to create review: %w", err)
+	}
+	log.Printf("Posted review with %d inline comments on %s/%s#%d", len(inline), owner, repo, pullNumber)
+	return nil
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// GateResult is the outcome of the merge-gate policy for a run.
+type GateResult struct {
+	Passed      bool      `json:"passed"`
+	Reasons     []string  `json:"reasons,omitempty"` // One per failed rule
+	Rules       int       `json:"rules"`             // Number of rules evaluated
+	EvaluatedAt time.Time `json:"evaluated_at"`
+
+	// ServerPolicy is set when the rules come from the server rather than the repository.
+	// Only such a gate decides the conclusion of the check run.
+	ServerPolicy bool `json:"server_policy"`
+}
@@ -1,18 +1,5 @@
 package models
 
-type Severity string
-
-const (
-	SeverityError   Severity = "error"
-	SeverityWarning Severity = "warning"
-	SeverityInfo    Severity = "info"
-)
-
-
-
-
-
-
 type Issue struct {
 	Path        string   // File path
 	Line        int      // Line number
@@ -22,6 +9,15 @@ type Issue struct {
 	Description string   // Detailed description
 	Suggestion  string   // Suggested fix (optional)
 	Source      string   // Source of the issue (e.g., "golangci-lint", "llm")
+	Category    string   // Issue category (e.g., "license", "vulnerability")
+	Fix         *CodeFix // Exact replacement for a line range (optional)
+}
+
+// CodeFix replaces lines StartLine..EndLine (inclusive, new-file numbering) with Replacement.
+type CodeFix struct {
+	StartLine   int
+	EndLine     int
+	Replacement string
 }
 
 type AffectedVersion struct {
@@ -40,10 +36,13 @@ type AffectedVersion struct {
 type File struct {
 	Path    string // File path
 	Content string // File content
+	Status  string // Change status (e.g., "added", "modified")
+	Patch   string // Unified diff patch for the file (optional)
 }
 
 type ReviewComment struct {
 	Path      string // File path
+	StartLine int    // First line of a multi-line comment (optional)
 	Line      int    // Line number
 	Body      string // Comment body
 	CommitID  string // Commit ID
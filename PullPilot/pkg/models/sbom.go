@@ -0,0 +1,16 @@
+package models
+
+type DependencyChange struct {
+	Dependency Dependency
+	OldVersion string // Version in the base revision, empty for added dependencies
+}
+
+type DependencyDiff struct {
+	Added   []Dependency
+	Removed []Dependency
+	Changed []DependencyChange
+}
+
+func (d *DependencyDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is an ordered issue severity: SeverityInfo < SeverityWarning < SeverityError.
+// It is encoded as its name in JSON and text.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// AtLeast reports whether s is as severe as min or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return s >= min
+}
+
+// ParseSeverity accepts the severity names used by PullPilot, linters and LLMs
+// ("error", "high", "warning", "medium", "info", "low", ...), case-insensitively.
+func ParseSeverity(value string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "error", "critical", "high", "fatal", "blocker":
+		return SeverityError, nil
+	case "warning", "warn", "medium", "moderate":
+		return SeverityWarning, nil
+	case "info", "low", "note", "notice", "suggestion", "hint":
+		return SeverityInfo, nil
+	default:
+		return SeverityInfo, fmt.Errorf("unknown severity %q", value)
+	}
+}
+
+// ParseSeverityOrDefault is ParseSeverity that falls back to def for empty or unknown values.
+func ParseSeverityOrDefault(value string, def Severity) Severity {
+	severity, err := ParseSeverity(value)
+	if err != nil {
+		return def
+	}
+	return severity
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
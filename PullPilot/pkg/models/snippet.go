@@ -0,0 +1,13 @@
+package models
+
+// CodeSnippet is a piece of code from elsewhere in the repository that helps review a change,
+// such as the definition of a referenced symbol or a caller of a changed function.
+type CodeSnippet struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Kind      string // "definition" or "caller"
+	Symbol    string
+	Code      string
+	Score     int // Higher is more relevant
+}
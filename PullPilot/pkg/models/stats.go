@@ -0,0 +1,35 @@
+package models
+
+// AIStats summarizes one run of the AI analyzer.
+type AIStats struct {
+	FilesAnalyzed         int           `json:"files_analyzed"`
+	EstimatedPromptTokens int           `json:"estimated_prompt_tokens"`
+	SkippedFiles          []SkippedFile `json:"skipped_files,omitempty"`
+	ContextSnippets       int           `json:"context_snippets"` // Related code from other files added to prompts
+
+	Responses       int `json:"responses"`
+	ParseFailures   int `json:"parse_failures"`
+	RepairAttempts  int `json:"repair_attempts"`
+	RepairSuccesses int `json:"repair_successes"`
+
+	CacheHits     int     `json:"cache_hits"`
+	CacheMisses   int     `json:"cache_misses"`
+	CacheHitRatio float64 `json:"cache_hit_ratio"` // Share of prompts answered from the response cache
+
+	SuggestedFixes int `json:"suggested_fixes"`
+	RejectedFixes  int `json:"rejected_fixes"` // Replacements that did not apply cleanly to the file
+
+	// Hallucination guard: findings moved to the line they describe, kept as low confidence,
+	// or dropped because they matched no code near the cited line.
+	GroundingSnapped int `json:"grounding_snapped"`
+	GroundingMarked  int `json:"grounding_marked"`
+	GroundingDropped int `json:"grounding_dropped"`
+
+	Calls []LLMCall   `json:"calls,omitempty"`
+	Usage UsageTotals `json:"usage"`
+}
+
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// PRSummary is the AI-generated walkthrough of a pull request.
+type PRSummary struct {
+	Overview    string        `json:"overview"`
+	Files       []FileSummary `json:"files"`
+	RiskAreas   []string      `json:"risk_areas"`
+	ReviewOrder []string      `json:"review_order"`
+}
+
+type FileSummary struct {
+	Path    string `json:"path"`
+	Summary string `json:"summary"`
+}
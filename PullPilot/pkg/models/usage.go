@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// LLMCall records the token usage, cost and latency of a single LLM request.
+type LLMCall struct {
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	Purpose          string    `json:"purpose"` // "review", "repair" or "summary"
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Estimated        bool      `json:"estimated"` // Token counts are estimates, the provider reported none
+	LatencyMS        int64     `json:"latency_ms"`
+	CostUSD          float64   `json:"cost_usd"`
+	At               time.Time `json:"at"`
+}
+
+// UsageTotals is a roll-up of LLM calls.
+type UsageTotals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	LatencyMS        int64   `json:"latency_ms"`
+}
+
+func (u *UsageTotals) Add(call LLMCall) {
+	u.Calls++
+	u.PromptTokens += call.PromptTokens
+	u.CompletionTokens += call.CompletionTokens
+	u.CostUSD += call.CostUSD
+	u.LatencyMS += call.LatencyMS
+}
+
+func (u *UsageTotals) Merge(other UsageTotals) {
+	u.Calls += other.Calls
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CostUSD += other.CostUSD
+	u.LatencyMS += other.LatencyMS
+}
@@ -5,6 +5,7 @@ type Dependency struct {
 	System  string
 	Name    string
 	Version string
+	License string
 }
 
 